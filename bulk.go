@@ -0,0 +1,168 @@
+/*
+ * Copyright (c) CERN 2016
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gfal2
+
+// #cgo pkg-config: gfal2 gfal_transfer
+// #include <gfal_api.h>
+import "C"
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+// CopyPair is a source and destination to be copied by CopyFiles.
+type CopyPair struct {
+	Source      string
+	Destination string
+	// Checksum type and expected value for this pair. If Checksum is empty, the checksum configured
+	// with SetChecksum is used. If only ChecksumType is empty, the type configured with SetChecksum is used.
+	// The checksum mode is always the one configured with SetChecksum.
+	ChecksumType string
+	Checksum     string
+}
+
+// State of a bulk copy in progress, used to tell the listeners which pair a callback refers to.
+type bulkCopy struct {
+	mutex   sync.Mutex
+	pairs   []CopyPair
+	current int
+}
+
+// Returns the index of the pair with the given urls, and remember it as the current one.
+func (bulk *bulkCopy) markerPair(source, destination string) int {
+	bulk.mutex.Lock()
+	defer bulk.mutex.Unlock()
+	for i, pair := range bulk.pairs {
+		if pair.Source == source && pair.Destination == destination {
+			bulk.current = i
+			break
+		}
+	}
+	return bulk.current
+}
+
+// Events do not carry the urls, so they are attributed to the pair whose source appears in the
+// description, if any, or otherwise to the last pair seen.
+func (bulk *bulkCopy) eventPair(description string) int {
+	bulk.mutex.Lock()
+	defer bulk.mutex.Unlock()
+	longest := 0
+	for i, pair := range bulk.pairs {
+		if len(pair.Source) > longest && strings.Contains(description, pair.Source) {
+			bulk.current = i
+			longest = len(pair.Source)
+		}
+	}
+	return bulk.current
+}
+
+// CopyFiles copies several files with a single call, which some protocols, as SRM or HTTP third party copies,
+// can run as a batch. Protocols that do not support it copy the files one after the other.
+// The listeners receive the index of the pair in Marker.Pair and Event.Pair.
+// It returns one error per pair, in the same order, nil for those copied successfully.
+// If a pair has a checksum value but no type is known for it, nothing is copied, and all pairs fail with EINVAL.
+func (params TransferHandler) CopyFiles(pairs []CopyPair) []GError {
+	defer params.registry.closeStreams()
+
+	nPairs := len(pairs)
+	errors := make([]GError, nPairs)
+	if nPairs == 0 {
+		return errors
+	}
+
+	// Checksums are passed as "type:value", so a type is needed for every pair with a value
+	_, defaultType, _ := params.GetChecksum()
+	for i, pair := range pairs {
+		if pair.Checksum != "" && pair.ChecksumType == "" && defaultType == "" {
+			for j := range errors {
+				errors[j] = newOpError("copy", gErrorImpl{
+					code:    syscall.EINVAL,
+					message: fmt.Sprintf("pair %d has a checksum, but no checksum type", i),
+				}, pairs[j].Source, pairs[j].Destination)
+			}
+			return errors
+		}
+	}
+
+	cSources := make([]*C.char, nPairs)
+	cDestinations := make([]*C.char, nPairs)
+	cChecksums := make([]*C.char, nPairs)
+	hasChecksums := false
+
+	for i, pair := range pairs {
+		cSources[i] = C.CString(pair.Source)
+		cDestinations[i] = C.CString(pair.Destination)
+		if pair.Checksum != "" {
+			chkType := pair.ChecksumType
+			if chkType == "" {
+				chkType = defaultType
+			}
+			cChecksums[i] = C.CString(chkType + ":" + pair.Checksum)
+			hasChecksums = true
+		}
+	}
+	defer func() {
+		for i := 0; i < nPairs; i++ {
+			C.free(unsafe.Pointer(cSources[i]))
+			C.free(unsafe.Pointer(cDestinations[i]))
+			C.free(unsafe.Pointer(cChecksums[i]))
+		}
+	}()
+
+	var checksumsPtr **C.char
+	if hasChecksums {
+		checksumsPtr = &cChecksums[0]
+	}
+
+	params.registry.setBulk(&bulkCopy{pairs: pairs})
+	defer params.registry.setBulk(nil)
+
+	var err *C.GError
+	var cFileErrors **C.GError
+
+	ret := C.gfalt_copy_bulk(params.cContext, params.cParams, C.size_t(nPairs),
+		&cSources[0], &cDestinations[0], checksumsPtr, &err, &cFileErrors)
+
+	if cFileErrors != nil {
+		fileErrors := unsafe.Slice(cFileErrors, nPairs)
+		for i, fileError := range fileErrors {
+			if fileError != nil {
+				errors[i] = opErrorCtoGo("copy", fileError, pairs[i].Source, pairs[i].Destination)
+			}
+		}
+		C.g_free(C.gpointer(unsafe.Pointer(cFileErrors)))
+	}
+
+	// A global error, with no details per file, applies to all of them
+	if err != nil {
+		opErr := errorCtoGo(err)
+		if cFileErrors == nil {
+			for i := range errors {
+				errors[i] = newOpError("copy", opErr, pairs[i].Source, pairs[i].Destination)
+			}
+		}
+	} else if ret < 0 && cFileErrors == nil {
+		for i := range errors {
+			errors[i] = newOpError("copy", gErrorImpl{code: syscall.EIO, message: "bulk copy failed"}, pairs[i].Source, pairs[i].Destination)
+		}
+	}
+
+	return errors
+}
@@ -0,0 +1,118 @@
+package gfal2
+
+import (
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+)
+
+type pairListener struct {
+	mutex sync.Mutex
+	pairs map[int]bool
+}
+
+func (l *pairListener) NotifyPerformanceMarker(marker Marker) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.pairs[marker.Pair] = true
+}
+
+func (l *pairListener) NotifyEvent(event Event) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.pairs[event.Pair] = true
+}
+
+func TestBulkPairAttribution(t *testing.T) {
+	bulk := &bulkCopy{pairs: []CopyPair{
+		{Source: "file:///data/a", Destination: "file:///copy/a"},
+		{Source: "file:///data/a.1", Destination: "file:///copy/a.1"},
+	}}
+	if pair := bulk.markerPair("file:///data/a.1", "file:///copy/a.1"); pair != 1 {
+		t.Error("Was expecting pair 1, got ", pair)
+	}
+	if pair := bulk.eventPair("file:///data/a => file:///copy/a"); pair != 0 {
+		t.Error("Was expecting pair 0, got ", pair)
+	}
+	// The longest match wins
+	if pair := bulk.eventPair("file:///data/a.1 => file:///copy/a.1"); pair != 1 {
+		t.Error("Was expecting pair 1, got ", pair)
+	}
+	// Otherwise, the last one seen
+	if pair := bulk.eventPair("no url"); pair != 1 {
+		t.Error("Was expecting pair 1, got ", pair)
+	}
+}
+
+func TestCopyFiles(t *testing.T) {
+	context := getContext(t)
+	handler, err := context.NewTransferHandler()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer handler.Close()
+	handler.SetChecksum(ChecksumBoth, "adler32", "")
+
+	good, _ := writeLocalFile(t, 4096)
+	other, _ := writeLocalFile(t, 1024)
+	checksum, lerr := localChecksum(strings.TrimPrefix(good, "file://"), "adler32")
+	if lerr != nil {
+		t.Fatal(lerr)
+	}
+	dir := "file://" + t.TempDir()
+
+	pairs := []CopyPair{
+		// The type configured on the handler is used
+		{Source: good, Destination: dir + "/good", Checksum: checksum},
+		{Source: "file://" + filepath.Join(t.TempDir(), "missing"), Destination: dir + "/missing"},
+		{Source: other, Destination: dir + "/other"},
+		{Source: good, Destination: dir + "/mismatch", ChecksumType: "adler32", Checksum: "00000001"},
+	}
+
+	listener := &pairListener{pairs: make(map[int]bool)}
+	handler.AddEventCallback(listener)
+	handler.AddMonitorCallback(listener)
+
+	errors := handler.CopyFiles(pairs)
+	if len(errors) != len(pairs) {
+		t.Fatal("Was expecting one error per pair, got ", len(errors))
+	}
+	if errors[0] != nil || errors[2] != nil {
+		t.Error("The good pairs failed: ", errors[0], errors[2])
+	}
+	if errors[1] == nil || !strings.Contains(errors[1].Error(), "missing") {
+		t.Error("Was expecting an error for the missing source, got ", errors[1])
+	}
+	if errors[3] == nil {
+		t.Error("Was expecting a checksum mismatch")
+	}
+
+	listener.mutex.Lock()
+	defer listener.mutex.Unlock()
+	for pair := range listener.pairs {
+		if pair < 0 || pair >= len(pairs) {
+			t.Error("Unexpected pair index: ", pair)
+		}
+	}
+	if !listener.pairs[0] || !listener.pairs[2] {
+		t.Error("Was expecting callbacks for the pairs 0 and 2, got ", listener.pairs)
+	}
+}
+
+func TestCopyFilesChecksumWithoutType(t *testing.T) {
+	context := getContext(t)
+	handler, err := context.NewTransferHandler()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer handler.Close()
+
+	source, _ := writeLocalFile(t, 16)
+	destination := "file://" + filepath.Join(t.TempDir(), "copy")
+	errors := handler.CopyFiles([]CopyPair{{Source: source, Destination: destination, Checksum: "00000001"}})
+	if code, _ := errorCode(errors[0]); code != syscall.EINVAL {
+		t.Error("Was expecting EINVAL, got ", errors[0])
+	}
+}
@@ -0,0 +1,135 @@
+/*
+ * Copyright (c) CERN 2016
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gfal2
+
+import (
+	"reflect"
+	"runtime/cgo"
+	"sync"
+)
+
+// callbackRegistry holds the listeners of a TransferHandler.
+// gfal2 only sees a cgo.Handle to it, which is released when the handler is closed.
+type callbackRegistry struct {
+	handle cgo.Handle
+
+	mutex            sync.Mutex
+	monitorListeners []MonitorListener
+	eventListeners   []EventListener
+	// True once the dispatching callbacks are registered with gfal2
+	monitorInstalled bool
+	eventInstalled   bool
+	// Bulk copy in progress, if any
+	bulk *bulkCopy
+	// Functions that close the channels returned by MarkerChannel and EventChannel
+	streams []func()
+}
+
+func newCallbackRegistry() *callbackRegistry {
+	registry := &callbackRegistry{}
+	registry.handle = cgo.NewHandle(registry)
+	return registry
+}
+
+// Release the handle, and close the pending channels. It is safe to call it more than once.
+func (registry *callbackRegistry) release() {
+	registry.closeStreams()
+
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+	if registry.handle != 0 {
+		registry.handle.Delete()
+		registry.handle = 0
+	}
+	registry.monitorListeners = nil
+	registry.eventListeners = nil
+}
+
+// Returns a snapshot of the listeners, so they can be called without holding the lock.
+func (registry *callbackRegistry) listeners() ([]MonitorListener, []EventListener) {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+	return append([]MonitorListener(nil), registry.monitorListeners...),
+		append([]EventListener(nil), registry.eventListeners...)
+}
+
+// Returns true if a and b are the same listener. Values that can not be compared never match.
+func sameListener(a, b any) bool {
+	if reflect.TypeOf(a) != reflect.TypeOf(b) || !reflect.TypeOf(a).Comparable() {
+		return false
+	}
+	return a == b
+}
+
+// Remove the first occurrence of listener. Returns false if it was not registered.
+func (registry *callbackRegistry) removeMonitorListener(listener MonitorListener) bool {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+	for i, registered := range registry.monitorListeners {
+		if sameListener(registered, listener) {
+			registry.monitorListeners = append(registry.monitorListeners[:i], registry.monitorListeners[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Remove the first occurrence of listener. Returns false if it was not registered.
+func (registry *callbackRegistry) removeEventListener(listener EventListener) bool {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+	for i, registered := range registry.eventListeners {
+		if sameListener(registered, listener) {
+			registry.eventListeners = append(registry.eventListeners[:i], registry.eventListeners[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Set the bulk copy in progress, or nil when it is over.
+func (registry *callbackRegistry) setBulk(bulk *bulkCopy) {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+	registry.bulk = bulk
+}
+
+// Returns the bulk copy in progress, or nil.
+func (registry *callbackRegistry) currentBulk() *bulkCopy {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+	return registry.bulk
+}
+
+// Register a function to be called when the current copy finishes.
+func (registry *callbackRegistry) addStream(closeStream func()) {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+	registry.streams = append(registry.streams, closeStream)
+}
+
+// Close the channels, and detach them from the handler.
+func (registry *callbackRegistry) closeStreams() {
+	registry.mutex.Lock()
+	streams := registry.streams
+	registry.streams = nil
+	registry.mutex.Unlock()
+
+	for _, closeStream := range streams {
+		closeStream()
+	}
+}
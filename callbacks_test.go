@@ -0,0 +1,56 @@
+package gfal2
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+type countingListener struct {
+	markers int
+	events  int
+}
+
+func (l *countingListener) NotifyPerformanceMarker(marker Marker) {
+	l.markers++
+}
+
+func (l *countingListener) NotifyEvent(event Event) {
+	l.events++
+}
+
+func TestCallbackRegistry(t *testing.T) {
+	context := getContext(t)
+	handler, err := context.NewTransferHandler()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	kept, removed := &countingListener{}, &countingListener{}
+	handler.AddEventCallback(kept)
+	handler.AddEventCallback(removed)
+	if err := handler.RemoveEventCallback(removed); err != nil {
+		t.Fatal(err)
+	}
+	if err := handler.RemoveEventCallback(removed); err == nil {
+		t.Error("Removing a listener twice should fail")
+	}
+
+	source, _ := writeLocalFile(t, 1024)
+	destination := "file://" + filepath.Join(t.TempDir(), "copy")
+	if err := handler.CopyFile(source, destination); err != nil {
+		t.Fatal(err)
+	}
+	if kept.events == 0 {
+		t.Error("The listener was not called")
+	}
+	if removed.events != 0 {
+		t.Error("A removed listener was called")
+	}
+
+	if err := handler.Close(); err != nil {
+		t.Error(err)
+	}
+	if handler.registry.handle != 0 {
+		t.Error("The registry was not released")
+	}
+}
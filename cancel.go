@@ -0,0 +1,257 @@
+/*
+ * Copyright (c) CERN 2016
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gfal2
+
+// #cgo pkg-config: gfal2 gfal_transfer
+// #include <gfal_api.h>
+import "C"
+import (
+	gocontext "context"
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"syscall"
+)
+
+// contextError is returned when an operation is aborted because its context.Context
+// was cancelled or its deadline expired.
+// It satisfies errors.Is(err, context.Canceled) and errors.Is(err, context.DeadlineExceeded).
+type contextError struct {
+	gErrorImpl
+	cause error
+	// Error returned by the aborted operation, if any
+	err GError
+}
+
+// Unwrap returns the context error that caused the abort, the error code, so errors.Is
+// matches both context.Canceled and syscall.ECANCELED, for instance, and the error returned
+// by the aborted operation, so errors.As can still reach its OpError.
+func (e contextError) Unwrap() []error {
+	if e.err != nil {
+		return []error{e.cause, e.code, e.err}
+	}
+	return []error{e.cause, e.code}
+}
+
+// Build a contextError from the context error and, if any, the error returned by gfal2.
+func newContextError(cause error, gerr GError) contextError {
+	var err contextError
+	err.cause = cause
+	err.code = syscall.ECANCELED
+	if errors.Is(cause, gocontext.DeadlineExceeded) {
+		err.code = syscall.ETIMEDOUT
+	}
+	err.message = cause.Error()
+	err.err = gerr
+	if gerr != nil {
+		err.domain = gerr.Domain()
+		err.message = cause.Error() + ": " + gerr.Error()
+	}
+	return err
+}
+
+// withContext runs op, cancelling the gfal2 operations running on cContext if ctx is done
+// before op returns.
+// Note that gfal2_cancel aborts *all* the operations running on the gfal2 context, so
+// concurrent calls sharing the same Context are cancelled together.
+func withContext(ctx gocontext.Context, cContext C.gfal2_context_t, op func() GError) GError {
+	if err := ctx.Err(); err != nil {
+		return newContextError(err, nil)
+	}
+
+	// The callback may still run after op returns, if ctx is done meanwhile, so it must not
+	// cancel whatever runs next on the same gfal2 context
+	var mutex sync.Mutex
+	finished := false
+	stop := gocontext.AfterFunc(ctx, func() {
+		mutex.Lock()
+		defer mutex.Unlock()
+		if !finished {
+			C.gfal2_cancel(cContext)
+		}
+	})
+	gerr := op()
+	mutex.Lock()
+	finished = true
+	mutex.Unlock()
+	stop()
+
+	if gerr != nil {
+		if err := ctx.Err(); err != nil {
+			return newContextError(err, gerr)
+		}
+	}
+	return gerr
+}
+
+// StatContext is like Stat, but aborts the operation when ctx is done.
+func (context Context) StatContext(ctx gocontext.Context, url string) (stat Stat, err GError) {
+	err = withContext(ctx, context.cContext, func() GError {
+		stat, err = context.Stat(url)
+		return err
+	})
+	return
+}
+
+// OpendirContext is like Opendir, but aborts the operation when ctx is done.
+func (context Context) OpendirContext(ctx gocontext.Context, url string) (dir *Dir, err GError) {
+	err = withContext(ctx, context.cContext, func() GError {
+		dir, err = context.Opendir(url)
+		return err
+	})
+	return
+}
+
+// OpenContext is like Open, but aborts the operation when ctx is done.
+func (context Context) OpenContext(ctx gocontext.Context, url string) (*File, GError) {
+	return context.OpenFileContext(ctx, url, os.O_RDONLY, 0)
+}
+
+// OpenFileContext is like OpenFile, but aborts the operation when ctx is done.
+func (context Context) OpenFileContext(ctx gocontext.Context, url string, flag int, perm os.FileMode) (fd *File, err GError) {
+	err = withContext(ctx, context.cContext, func() GError {
+		fd, err = context.OpenFile(url, flag, perm)
+		return err
+	})
+	return
+}
+
+// ReadContext is like Read, but aborts the operation when ctx is done.
+func (fd File) ReadContext(ctx gocontext.Context, b []byte) (int, error) {
+	if len(b) == 0 {
+		return 0, nil
+	}
+
+	var n int
+	err := withContext(ctx, fd.cContext, func() (err GError) {
+		n, err = fd.read(b)
+		return
+	})
+	if err != nil {
+		return 0, err
+	}
+	if n == 0 {
+		return 0, io.EOF
+	}
+	return n, nil
+}
+
+// WriteContext is like Write, but aborts the operation when ctx is done.
+func (fd File) WriteContext(ctx gocontext.Context, b []byte) (int, error) {
+	if len(b) == 0 {
+		return 0, nil
+	}
+
+	var n int
+	err := withContext(ctx, fd.cContext, func() (err GError) {
+		n, err = fd.write(b)
+		return
+	})
+	if err != nil {
+		return n, err
+	}
+	return n, nil
+}
+
+// ChecksumContext is like Checksum, but aborts the operation when ctx is done.
+func (context Context) ChecksumContext(ctx gocontext.Context, url string, chktype string, offset uint64, length uint64) (checksum string, err GError) {
+	err = withContext(ctx, context.cContext, func() GError {
+		checksum, err = context.Checksum(url, chktype, offset, length)
+		return err
+	})
+	return
+}
+
+// BringOnlineContext is like BringOnline, but aborts the operation when ctx is done.
+func (context Context) BringOnlineContext(ctx gocontext.Context, url string, pintime int, timeout int, async bool) (token string, err GError) {
+	err = withContext(ctx, context.cContext, func() GError {
+		token, err = context.BringOnline(url, pintime, timeout, async)
+		return err
+	})
+	return
+}
+
+// BringOnlinePollContext is like BringOnlinePoll, but aborts the operation when ctx is done.
+func (context Context) BringOnlinePollContext(ctx gocontext.Context, url string, token string) GError {
+	return withContext(ctx, context.cContext, func() GError {
+		return context.BringOnlinePoll(url, token)
+	})
+}
+
+// BringOnlineListContext is like BringOnlineList, but aborts the operation when ctx is done.
+// If ctx is done, all the entries of the returned list carry the context error.
+func (context Context) BringOnlineListContext(ctx gocontext.Context, urls []string, pintime int, timeout int, async bool) (token string, errors []GError) {
+	withContext(ctx, context.cContext, func() GError {
+		token, errors = context.BringOnlineList(urls, pintime, timeout, async)
+		return nil
+	})
+	return token, contextErrorList(ctx, len(urls), errors)
+}
+
+// BringOnlinePollListContext is like BringOnlinePollList, but aborts the operation when ctx is done.
+// If ctx is done, all the entries of the returned list carry the context error.
+func (context Context) BringOnlinePollListContext(ctx gocontext.Context, urls []string, token string) (errors []GError) {
+	withContext(ctx, context.cContext, func() GError {
+		errors = context.BringOnlinePollList(urls, token)
+		return nil
+	})
+	return contextErrorList(ctx, len(urls), errors)
+}
+
+// Replace the entries of a list of errors with the context error, if ctx is done.
+func contextErrorList(ctx gocontext.Context, nItems int, errors []GError) []GError {
+	cause := ctx.Err()
+	if cause == nil {
+		return errors
+	}
+	if errors == nil {
+		errors = make([]GError, nItems)
+	}
+	for i := range errors {
+		errors[i] = newContextError(cause, errors[i])
+	}
+	return errors
+}
+
+// MkdirContext is like Mkdir, but aborts the operation when ctx is done.
+func (context Context) MkdirContext(ctx gocontext.Context, url string, mode os.FileMode) GError {
+	return withContext(ctx, context.cContext, func() GError {
+		return context.Mkdir(url, mode)
+	})
+}
+
+// MkdirAllContext is like MkdirAll, but aborts the operation when ctx is done.
+func (context Context) MkdirAllContext(ctx gocontext.Context, url string, mode os.FileMode) GError {
+	return withContext(ctx, context.cContext, func() GError {
+		return context.MkdirAll(url, mode)
+	})
+}
+
+// RemoveContext is like Remove, but aborts the operation when ctx is done.
+func (context Context) RemoveContext(ctx gocontext.Context, url string) GError {
+	return withContext(ctx, context.cContext, func() GError {
+		return context.Remove(url)
+	})
+}
+
+// CopyFileContext is like CopyFile, but aborts the transfer when ctx is done.
+func (params TransferHandler) CopyFileContext(ctx gocontext.Context, source string, destination string) GError {
+	return withContext(ctx, params.cContext, func() GError {
+		return params.CopyFile(source, destination)
+	})
+}
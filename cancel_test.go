@@ -0,0 +1,37 @@
+package gfal2
+
+import (
+	gocontext "context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBringOnlineDeadline(t *testing.T) {
+	context := getContext(t)
+	ctx, cancel := gocontext.WithTimeout(gocontext.Background(), 1*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	_, err := context.BringOnlineContext(ctx, "mock://host/file?staging_time=30", 100, 100, false)
+	if err == nil {
+		t.Fatal("Expecting an error")
+	}
+	if !errors.Is(err, gocontext.DeadlineExceeded) {
+		t.Error("Was expecting DeadlineExceeded, got ", err)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Error("The operation was not cancelled in time: ", elapsed)
+	}
+}
+
+func TestStatAlreadyCancelled(t *testing.T) {
+	context := getContext(t)
+	ctx, cancel := gocontext.WithCancel(gocontext.Background())
+	cancel()
+
+	_, err := context.StatContext(ctx, "mock://host/file")
+	if !errors.Is(err, gocontext.Canceled) {
+		t.Error("Was expecting Canceled, got ", err)
+	}
+}
@@ -17,11 +17,12 @@
 package gfal2
 
 /*
+#include <stdint.h>
 #include <gfal_api.h>
 
 void logHandlerWrapper(const char*, GLogLevelFlags, const char*, gpointer);
-void eventCallbackWrapper(const gfalt_event_t e, gpointer user_data);
-void monitorCallbackWrapper(gfalt_transfer_status_t h, const char* src, const char *dst, gpointer user_data);
+void eventCallbackWrapper(const gfalt_event_t e, uintptr_t user_data);
+void monitorCallbackWrapper(gfalt_transfer_status_t h, const char* src, const char *dst, uintptr_t user_data);
 
 void logCallback(const gchar *log_domain, GLogLevelFlags log_level,
 	const gchar *message, gpointer user_data)
@@ -32,13 +33,37 @@ void logCallback(const gchar *log_domain, GLogLevelFlags log_level,
 
 void monitorCallback(gfalt_transfer_status_t h, const char* src, const char* dst, gpointer user_data)
 {
-	monitorCallbackWrapper(h, src, dst, user_data);
+	monitorCallbackWrapper(h, src, dst, (uintptr_t)user_data);
 }
 
 
 void eventCallback(const gfalt_event_t e, gpointer user_data)
 {
-	eventCallbackWrapper(e, user_data);
+	eventCallbackWrapper(e, (uintptr_t)user_data);
+}
+
+
+gint addMonitorCallback(gfalt_params_t params, uintptr_t handle, GError** err)
+{
+	return gfalt_add_monitor_callback(params, monitorCallback, (gpointer)handle, NULL, err);
+}
+
+
+gint removeMonitorCallback(gfalt_params_t params, GError** err)
+{
+	return gfalt_remove_monitor_callback(params, monitorCallback, err);
+}
+
+
+gint addEventCallback(gfalt_params_t params, uintptr_t handle, GError** err)
+{
+	return gfalt_add_event_callback(params, eventCallback, (gpointer)handle, NULL, err);
+}
+
+
+gint removeEventCallback(gfalt_params_t params, GError** err)
+{
+	return gfalt_remove_event_callback(params, eventCallback, err);
 }
 
 */
@@ -36,7 +36,7 @@ func (context Context) SetOptString(group string, key string, value string) GErr
 
 	ret := C.gfal2_set_opt_string(context.cContext, cGroup, cKey, cValue, &err)
 	if ret < 0 {
-		return errorCtoGo(err)
+		return opErrorCtoGo("set_opt_string", err)
 	}
 
 	return nil
@@ -72,7 +72,7 @@ func (context Context) SetOptInteger(group string, key string, value int) GError
 
 	ret := C.gfal2_set_opt_integer(context.cContext, cGroup, cKey, C.gint(value), &err)
 	if ret < 0 {
-		return errorCtoGo(err)
+		return opErrorCtoGo("set_opt_integer", err)
 	}
 
 	return nil
@@ -111,7 +111,7 @@ func (context Context) SetOptBoolean(group string, key string, value bool) GErro
 
 	ret := C.gfal2_set_opt_boolean(context.cContext, cGroup, cKey, C.gboolean(cValue), &err)
 	if ret < 0 {
-		return errorCtoGo(err)
+		return opErrorCtoGo("set_opt_boolean", err)
 	}
 
 	return nil
@@ -153,7 +153,7 @@ func (context Context) SetOptStringList(group string, key string, values []strin
 
 	ret := C.gfal2_set_opt_string_list(context.cContext, cGroup, cKey, &cValues[0], C.gsize(nValues), &err)
 	if ret < 0 {
-		return errorCtoGo(err)
+		return opErrorCtoGo("set_opt_string_list", err)
 	}
 
 	return nil
@@ -196,7 +196,7 @@ func (context Context) LoadOptsFromFile(path string) GError {
 
 	ret := C.gfal2_load_opts_from_file(context.cContext, cPath, &err)
 	if ret < 0 {
-		return errorCtoGo(err)
+		return opErrorCtoGo("load_opts_from_file", err, path)
 	}
 
 	return nil
@@ -213,7 +213,7 @@ func (context Context) SetUserAgent(agent string, version string) GError {
 
 	ret := C.gfal2_set_user_agent(context.cContext, cAgent, cVersion, &err)
 	if ret < 0 {
-		return errorCtoGo(err)
+		return opErrorCtoGo("set_user_agent", err)
 	}
 
 	return nil
@@ -250,7 +250,7 @@ func (context Context) AddClientInfo(key string, value string) GError {
 
 	ret := C.gfal2_add_client_info(context.cContext, cKey, cValue, &err)
 	if ret < 0 {
-		return errorCtoGo(err)
+		return opErrorCtoGo("add_client_info", err)
 	}
 
 	return nil
@@ -265,7 +265,7 @@ func (context Context) RemoveClientInfo(key string) GError {
 
 	ret := C.gfal2_remove_client_info(context.cContext, cKey, &err)
 	if ret < 0 {
-		return errorCtoGo(err)
+		return opErrorCtoGo("remove_client_info", err)
 	}
 
 	return nil
@@ -277,7 +277,7 @@ func (context Context) ClearClientInfo() GError {
 
 	ret := C.gfal2_clear_client_info(context.cContext, &err)
 	if ret < 0 {
-		return errorCtoGo(err)
+		return opErrorCtoGo("clear_client_info", err)
 	}
 	return nil
 }
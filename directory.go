@@ -20,6 +20,7 @@ package gfal2
 // #include <gfal_api.h>
 import "C"
 import (
+	"iter"
 	"os"
 	"time"
 	"unsafe"
@@ -29,17 +30,19 @@ import (
 type Dir struct {
 	cDir     *C.DIR
 	cContext C.gfal2_context_t
+	url      string
 }
 
 // DirEntry models a directory entry.
+// It is a self-contained copy, so it remains valid after further calls to Readdir, or Close.
 type DirEntry struct {
-	cDirent *C.struct_dirent
-	cStat   C.struct_stat
+	name  string
+	cStat C.struct_stat
 }
 
-// Name returns the file name/
+// Name returns the file name.
 func (entry DirEntry) Name() string {
-	return C.GoString(&entry.cDirent.d_name[0])
+	return entry.name
 }
 
 // Size returns the file size.
@@ -101,9 +104,10 @@ func (context Context) Opendir(url string) (*Dir, GError) {
 
 	var dir Dir
 	dir.cContext = context.cContext
+	dir.url = url
 	dir.cDir = C.gfal2_opendir(context.cContext, cURL, &err)
 	if dir.cDir == nil {
-		return nil, errorCtoGo(err)
+		return nil, opErrorCtoGo("opendir", err, url)
 	}
 
 	return &dir, nil
@@ -111,25 +115,109 @@ func (context Context) Opendir(url string) (*Dir, GError) {
 
 // Readdir reads a single entry from the directory.
 // For the last entry, it returns nil, nil
-func (dir Dir) Readdir() (Stat, GError) {
+func (dir *Dir) Readdir() (Stat, GError) {
 	var err *C.GError
 	var entry DirEntry
 
-	entry.cDirent = C.gfal2_readdirpp(dir.cContext, dir.cDir, &entry.cStat, &err)
-	if entry.cDirent == nil && err != nil {
-		return nil, errorCtoGo(err)
-	} else if entry.cDirent == nil {
+	cDirent := C.gfal2_readdirpp(dir.cContext, dir.cDir, &entry.cStat, &err)
+	if cDirent == nil && err != nil {
+		return nil, opErrorCtoGo("readdir", err, dir.url)
+	} else if cDirent == nil {
 		return nil, nil
 	}
+	entry.name = C.GoString(&cDirent.d_name[0])
 	return entry, nil
 }
 
+// ReadName reads the name of the next entry from the directory using gfal2_readdir, so, unlike Readdir,
+// the entry is not stat'ed. This is much cheaper for protocols where listing metadata is expensive.
+// For the last entry, it returns "", nil
+func (dir *Dir) ReadName() (string, GError) {
+	var err *C.GError
+
+	cDirent := C.gfal2_readdir(dir.cContext, dir.cDir, &err)
+	if cDirent == nil && err != nil {
+		return "", opErrorCtoGo("readdir", err, dir.url)
+	} else if cDirent == nil {
+		return "", nil
+	}
+	return C.GoString(&cDirent.d_name[0]), nil
+}
+
+// ReadDirN reads up to n entries from the directory. If n <= 0, it reads all the remaining entries.
+// An empty list means the end of the directory has been reached.
+// On error, it returns the entries read so far together with the error.
+func (dir *Dir) ReadDirN(n int) ([]Stat, GError) {
+	var entries []Stat
+	for n <= 0 || len(entries) < n {
+		entry, err := dir.Readdir()
+		if err != nil {
+			return entries, err
+		} else if entry == nil {
+			break
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// All returns an iterator over the remaining entries of the directory, to be used with range.
+// On error, the error is yielded with a nil Stat, and the iteration ends.
+// The directory is closed once the iteration finishes, including when the loop body stops it early.
+func (dir *Dir) All() iter.Seq2[Stat, error] {
+	return func(yield func(Stat, error) bool) {
+		defer dir.Close()
+		for {
+			entry, err := dir.Readdir()
+			if err != nil {
+				yield(nil, err)
+				return
+			} else if entry == nil {
+				return
+			}
+			if !yield(entry, nil) {
+				return
+			}
+		}
+	}
+}
+
 // Close the directory and frees the associated memory.
-func (dir Dir) Close() GError {
+// Calling Close more than once is a no-op.
+func (dir *Dir) Close() GError {
+	if dir.cDir == nil {
+		return nil
+	}
+
 	var err *C.GError
 	ret := C.gfal2_closedir(dir.cContext, dir.cDir, &err)
+	dir.cDir = nil
 	if ret < 0 {
-		return errorCtoGo(err)
+		return opErrorCtoGo("closedir", err, dir.url)
 	}
 	return nil
 }
+
+// ReadDirNames returns the names of the entries of the directory, without retrieving their metadata.
+// The entries "." and ".." are not included.
+func (context Context) ReadDirNames(url string) ([]string, GError) {
+	dir, err := context.Opendir(url)
+	if err != nil {
+		return nil, err
+	}
+	defer dir.Close()
+
+	names := []string{}
+	for {
+		name, err := dir.ReadName()
+		if err != nil {
+			return nil, err
+		} else if name == "" {
+			break
+		}
+		if name != "." && name != ".." {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
@@ -0,0 +1,114 @@
+package gfal2
+
+import (
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+// Returns the names of the entries, without "." and "..", sorted.
+func entryNames(entries []Stat) []string {
+	names := []string{}
+	for _, entry := range entries {
+		if name := entry.Name(); name != "." && name != ".." {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestReadDirN(t *testing.T) {
+	context := getContext(t)
+	root := makeTree(t, "1", "2", "3", "4", "5")
+
+	dir, err := context.Opendir("file://" + root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dir.Close()
+
+	var all []Stat
+	for {
+		entries, err := dir.ReadDirN(2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) > 2 {
+			t.Fatal("Got more entries than requested: ", len(entries))
+		}
+		if len(entries) == 0 {
+			break
+		}
+		all = append(all, entries...)
+	}
+	if names := entryNames(all); len(names) != 5 || names[0] != "1" || names[4] != "5" {
+		t.Error("Unexpected entries: ", names)
+	}
+
+	if entries, err := dir.ReadDirN(0); err != nil || len(entries) != 0 {
+		t.Error("Was expecting no more entries: ", entries, err)
+	}
+}
+
+func TestReadDirNames(t *testing.T) {
+	context := getContext(t)
+	root := makeTree(t, "1", "2", "sub/")
+
+	names, err := context.ReadDirNames("file://" + root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(names)
+	if len(names) != 3 || names[0] != "1" || names[1] != "2" || names[2] != "sub" {
+		t.Error("Unexpected names: ", names)
+	}
+
+	if _, err := context.ReadDirNames("file://" + filepath.Join(root, "missing")); !errors.Is(err, fs.ErrNotExist) {
+		t.Error("Was expecting ErrNotExist, got ", err)
+	}
+}
+
+func TestDirAll(t *testing.T) {
+	context := getContext(t)
+	root := makeTree(t, "1", "2", "3", "sub/")
+
+	dir, err := context.Opendir("file://" + root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var all []Stat
+	for entry, err := range dir.All() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		all = append(all, entry)
+	}
+	if names := entryNames(all); len(names) != 4 || names[3] != "sub" {
+		t.Error("Unexpected entries: ", names)
+	}
+	if dir.cDir != nil {
+		t.Error("The directory should have been closed")
+	}
+
+	dir, err = context.Opendir("file://" + root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	count := 0
+	for range dir.All() {
+		count++
+		break
+	}
+	if count != 1 {
+		t.Error("Unexpected number of iterations: ", count)
+	}
+	if dir.cDir != nil {
+		t.Error("The directory should have been closed after breaking the loop")
+	}
+	if err := dir.Close(); err != nil {
+		t.Error("Closing again should be a no-op: ", err)
+	}
+}
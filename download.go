@@ -0,0 +1,222 @@
+/*
+ * Copyright (c) CERN 2016
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gfal2
+
+import (
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// DownloadOptions configures a parallel download. The zero value uses the defaults.
+type DownloadOptions struct {
+	// Number of byte ranges transferred concurrently. Defaults to 4.
+	Streams int
+	// Size of each byte range. If 0, the file is split into Streams ranges of the same size.
+	ChunkSize int64
+	// Number of times a range is retried on a transient error. Defaults to 3. Negative disables retries.
+	Retries int
+	// Time to wait between retries. Defaults to one second.
+	RetryDelay time.Duration
+}
+
+// Return a copy of opts with the defaults applied. opts can be nil.
+func (opts *DownloadOptions) withDefaults() DownloadOptions {
+	var final DownloadOptions
+	if opts != nil {
+		final = *opts
+	}
+	applyTransferDefaults(&final.Streams, &final.Retries, &final.RetryDelay)
+	return final
+}
+
+// Apply the defaults shared by DownloadOptions and UploadOptions.
+func applyTransferDefaults(streams *int, retries *int, retryDelay *time.Duration) {
+	if *streams <= 0 {
+		*streams = 4
+	}
+	if *retries == 0 {
+		*retries = 3
+	} else if *retries < 0 {
+		*retries = 0
+	}
+	if *retryDelay <= 0 {
+		*retryDelay = time.Second
+	}
+}
+
+// chunk is a byte range of a file.
+type chunk struct {
+	offset int64
+	length int64
+}
+
+// Split a file of the given size into ranges of chunkSize bytes, or into nStreams
+// ranges if chunkSize is 0.
+func splitChunks(size int64, nStreams int, chunkSize int64) []chunk {
+	if chunkSize <= 0 {
+		chunkSize = (size + int64(nStreams) - 1) / int64(nStreams)
+	}
+	if chunkSize <= 0 {
+		chunkSize = 1
+	}
+
+	var chunks []chunk
+	for offset := int64(0); offset < size; offset += chunkSize {
+		length := chunkSize
+		if offset+length > size {
+			length = size - offset
+		}
+		chunks = append(chunks, chunk{offset: offset, length: length})
+	}
+	return chunks
+}
+
+// Process the chunks using nWorkers goroutines. worker is the index of the goroutine
+// calling process, so it can keep per goroutine state.
+// Stops at the first error, and returns it.
+func processChunks(chunks []chunk, nWorkers int, process func(worker int, c chunk) error) error {
+	queue := make(chan chunk)
+	abort := make(chan struct{})
+
+	var wg sync.WaitGroup
+	var once sync.Once
+	var firstErr error
+
+	for w := 0; w < nWorkers; w++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			for c := range queue {
+				if err := process(worker, c); err != nil {
+					once.Do(func() {
+						firstErr = err
+						close(abort)
+					})
+					return
+				}
+			}
+		}(w)
+	}
+
+feed:
+	for _, c := range chunks {
+		select {
+		case queue <- c:
+		case <-abort:
+			break feed
+		}
+	}
+	close(queue)
+	wg.Wait()
+
+	return firstErr
+}
+
+// Download copies the remote url into localPath, reading several byte ranges concurrently
+// with positional reads.
+// The local file is created, or truncated, and preallocated to the size of the source.
+// Each range is retried independently on transient errors, resuming from the last byte received.
+// On failure, the local file is removed.
+func (context Context) Download(url string, localPath string, opts *DownloadOptions) error {
+	options := opts.withDefaults()
+
+	info, gerr := context.Stat(url)
+	if gerr != nil {
+		return gerr
+	}
+
+	local, err := os.OpenFile(localPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+
+	err = local.Truncate(info.Size())
+	if err == nil {
+		err = context.downloadChunks(url, local, splitChunks(info.Size(), options.Streams, options.ChunkSize), options)
+	}
+	if closeErr := local.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		os.Remove(localPath)
+	}
+	return err
+}
+
+// Download the chunks of url into local. Each worker keeps its own remote file descriptor.
+func (context Context) downloadChunks(url string, local *os.File, chunks []chunk, options DownloadOptions) error {
+	remotes := make([]*File, options.Streams)
+	defer func() {
+		for _, remote := range remotes {
+			if remote != nil {
+				remote.Close()
+			}
+		}
+	}()
+
+	return processChunks(chunks, options.Streams, func(worker int, c chunk) error {
+		bufferSize := int64(copyBufferSize)
+		if c.length < bufferSize {
+			bufferSize = c.length
+		}
+		buffer := make([]byte, bufferSize)
+
+		done := int64(0)
+		attempt := 0
+		for done < c.length {
+			var err error
+
+			if remotes[worker] == nil {
+				remotes[worker], err = context.Open(url)
+			}
+			if err == nil {
+				n := c.length - done
+				if n > bufferSize {
+					n = bufferSize
+				}
+
+				var read int
+				read, err = remotes[worker].ReadAt(buffer[:n], c.offset+done)
+				if read > 0 {
+					if _, writeErr := local.WriteAt(buffer[:read], c.offset+done); writeErr != nil {
+						return writeErr
+					}
+					done += int64(read)
+				}
+				if err == io.EOF {
+					err = io.ErrUnexpectedEOF
+				}
+			}
+
+			if err != nil {
+				if attempt >= options.Retries || !IsTransient(err) {
+					return err
+				}
+				attempt++
+				if remotes[worker] != nil {
+					remotes[worker].Close()
+					remotes[worker] = nil
+				}
+				time.Sleep(options.RetryDelay)
+			}
+		}
+		return nil
+	})
+}
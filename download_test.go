@@ -0,0 +1,71 @@
+package gfal2
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDownload(t *testing.T) {
+	context := getContext(t)
+	url, content := writeLocalFile(t, 1024*1024+17)
+	local := filepath.Join(t.TempDir(), "download")
+
+	err := context.Download(url, local, &DownloadOptions{Streams: 4, ChunkSize: 64 * 1024})
+	if err != nil {
+		t.Fatal(err)
+	}
+	downloaded, err := os.ReadFile(local)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(downloaded, content) {
+		t.Error("Unexpected content, got ", len(downloaded), " bytes")
+	}
+}
+
+func TestDownloadEmpty(t *testing.T) {
+	context := getContext(t)
+	url, _ := writeLocalFile(t, 0)
+	local := filepath.Join(t.TempDir(), "download")
+
+	if err := context.Download(url, local, nil); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(local); err != nil || info.Size() != 0 {
+		t.Error("Was expecting an empty file: ", info, err)
+	}
+}
+
+func TestDownloadFailure(t *testing.T) {
+	context := getContext(t)
+	dir := t.TempDir()
+	local := filepath.Join(dir, "download")
+
+	err := context.Download("file://"+filepath.Join(dir, "missing"), local, nil)
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Error("Was expecting ErrNotExist, got ", err)
+	}
+	if _, err := os.Stat(local); !os.IsNotExist(err) {
+		t.Error("The local file should not exist: ", err)
+	}
+
+	// A directory can be stat'ed, but not read, so the download fails once the local file is created
+	source := filepath.Join(dir, "source")
+	if err := os.Mkdir(source, 0755); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(source, "entry"), nil, 0644)
+	if info, _ := os.Stat(source); info.Size() == 0 {
+		t.Skip("Directories have no size on this filesystem")
+	}
+	if err := context.Download("file://"+source, local, &DownloadOptions{Retries: -1}); err == nil {
+		t.Error("Was expecting an error downloading a directory")
+	}
+	if _, err := os.Stat(local); !os.IsNotExist(err) {
+		t.Error("The local file should have been removed: ", err)
+	}
+}
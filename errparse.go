@@ -0,0 +1,191 @@
+/*
+ * Copyright (c) CERN 2016
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gfal2
+
+import (
+	"errors"
+	"strings"
+	"syscall"
+)
+
+// Sides of a transfer an error can be attributed to.
+const (
+	ErrorSideSource      = "SOURCE"
+	ErrorSideDestination = "DESTINATION"
+	ErrorSideTransfer    = "TRANSFER"
+)
+
+// ErrorDetails is the structured form of a gfal2 error message, such as
+// "[gfal2_stat][gfal_plugin_statG][davix2gliberr] Result HTTP 404 : File not found"
+// or "SOURCE CHECKSUM Checksum mismatch".
+type ErrorDetails struct {
+	// Chain of functions, outermost first, as in [gfal2_stat][gfal_plugin_statG].
+	Functions []string
+	// Plugin that raised the error (http, gridftp, xrootd, srm...), if it can be guessed.
+	Plugin string
+	// One of ErrorSideSource, ErrorSideDestination or ErrorSideTransfer; empty if not a transfer error.
+	Side string
+	// Stage of the transfer that failed, as CHECKSUM, OVERWRITE or MAKE_PARENT. May be empty.
+	Stage string
+	// Root cause, without the prefixes.
+	Cause string
+	// Error code and domain, if the error is a GError.
+	Code   syscall.Errno
+	Domain string
+}
+
+// Substrings of function names or domains, and the plugin they identify.
+var pluginHints = []struct {
+	hint   string
+	plugin string
+}{
+	{"davix", "http"},
+	{"http", "http"},
+	{"gridftp", "gridftp"},
+	{"globus", "gridftp"},
+	{"xrootd", "xrootd"},
+	{"xrd", "xrootd"},
+	{"srm", "srm"},
+	{"lfc", "lfc"},
+	{"dcap", "dcap"},
+	{"rfio", "rfio"},
+	{"sftp", "sftp"},
+	{"mock", "mock"},
+	{"lfile", "file"},
+	{"plugin_file", "file"},
+}
+
+// Guess the plugin from a function name or a domain.
+func guessPlugin(name string) string {
+	lower := strings.ToLower(name)
+	for _, hint := range pluginHints {
+		if strings.Contains(lower, hint.hint) {
+			return hint.plugin
+		}
+	}
+	return ""
+}
+
+// Return the side matching word, or an empty string.
+func parseSide(word string) string {
+	switch strings.ToUpper(word) {
+	case ErrorSideSource:
+		return ErrorSideSource
+	case ErrorSideDestination:
+		return ErrorSideDestination
+	case ErrorSideTransfer:
+		return ErrorSideTransfer
+	}
+	return ""
+}
+
+// Returns true if word looks like a stage identifier: upper case letters, digits and underscores.
+func isStageWord(word string) bool {
+	if len(word) < 2 {
+		return false
+	}
+	for _, c := range word {
+		if !(c >= 'A' && c <= 'Z') && !(c >= '0' && c <= '9') && c != '_' {
+			return false
+		}
+	}
+	return true
+}
+
+// ParseErrorMessage parses a gfal2 error message into its components.
+func ParseErrorMessage(msg string) ErrorDetails {
+	var details ErrorDetails
+	var stage []string
+
+	rest := strings.TrimSpace(msg)
+	for rest != "" {
+		if rest[0] == '[' {
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				break
+			}
+			token := strings.TrimSpace(rest[1:end])
+			rest = strings.TrimSpace(rest[end+1:])
+
+			if side := parseSide(token); side != "" && details.Side == "" {
+				details.Side = side
+			} else if details.Side != "" && len(stage) == 0 && isStageWord(token) {
+				stage = append(stage, token)
+			} else if token != "" {
+				details.Functions = append(details.Functions, token)
+			}
+			continue
+		}
+
+		word, remainder, _ := strings.Cut(rest, " ")
+		trimmed := strings.TrimSuffix(word, ":")
+		if side := parseSide(trimmed); side != "" && details.Side == "" && len(details.Functions) == 0 {
+			details.Side = side
+		} else if details.Side != "" && len(details.Functions) == 0 && isStageWord(trimmed) {
+			stage = append(stage, trimmed)
+		} else {
+			break
+		}
+		rest = strings.TrimSpace(remainder)
+		if trimmed != word {
+			// A colon ends the prefix
+			break
+		}
+	}
+
+	// "ERROR" is a filler, not a stage, as in "TRANSFER ERROR: ..."
+	if len(stage) == 1 && stage[0] == "ERROR" {
+		stage = nil
+	}
+	details.Stage = strings.Join(stage, " ")
+	details.Cause = strings.TrimSpace(strings.TrimPrefix(rest, ":"))
+
+	for i := len(details.Functions) - 1; i >= 0 && details.Plugin == ""; i-- {
+		details.Plugin = guessPlugin(details.Functions[i])
+	}
+	return details
+}
+
+// ParseError parses the message of a gfal2 error into its components.
+// If err is an OpError, or an operation aborted by its context.Context, the message of the
+// underlying gfal2 error is parsed.
+func ParseError(err error) ErrorDetails {
+	if err == nil {
+		return ErrorDetails{}
+	}
+
+	msg := err.Error()
+	var ctxErr contextError
+	if errors.As(err, &ctxErr) && ctxErr.err != nil {
+		msg = ctxErr.err.Error()
+	}
+	var opErr *OpError
+	if errors.As(err, &opErr) {
+		msg = opErr.Err.Error()
+	}
+	details := ParseErrorMessage(msg)
+
+	var gerr GError
+	if errors.As(err, &gerr) {
+		details.Code = gerr.Code()
+		details.Domain = gerr.Domain()
+		if plugin := guessPlugin(details.Domain); plugin != "" {
+			details.Plugin = plugin
+		}
+	}
+	return details
+}
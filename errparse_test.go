@@ -0,0 +1,72 @@
+package gfal2
+
+import (
+	"reflect"
+	"syscall"
+	"testing"
+)
+
+func TestParseErrorMessage(t *testing.T) {
+	cases := []struct {
+		msg      string
+		expected ErrorDetails
+	}{
+		{
+			"[gfal2_stat][gfal_plugin_statG][davix2gliberr] Result HTTP 404 : File not found  after 1 attempts",
+			ErrorDetails{
+				Functions: []string{"gfal2_stat", "gfal_plugin_statG", "davix2gliberr"},
+				Plugin:    "http",
+				Cause:     "Result HTTP 404 : File not found  after 1 attempts",
+			},
+		},
+		{
+			"SOURCE CHECKSUM Checksum mismatch",
+			ErrorDetails{Side: ErrorSideSource, Stage: "CHECKSUM", Cause: "Checksum mismatch"},
+		},
+		{
+			"TRANSFER CHECKSUM MISMATCH Source and destination checksums do not match",
+			ErrorDetails{Side: ErrorSideTransfer, Stage: "CHECKSUM MISMATCH", Cause: "Source and destination checksums do not match"},
+		},
+		{
+			"TRANSFER ERROR: Copy failed with mode 3rd pull",
+			ErrorDetails{Side: ErrorSideTransfer, Cause: "Copy failed with mode 3rd pull"},
+		},
+		{
+			"DESTINATION MAKE_PARENT [gfal2_mkdir_rec][gfal_gridftp_mkdirG] Permission denied",
+			ErrorDetails{
+				Functions: []string{"gfal2_mkdir_rec", "gfal_gridftp_mkdirG"},
+				Plugin:    "gridftp",
+				Side:      ErrorSideDestination,
+				Stage:     "MAKE_PARENT",
+				Cause:     "Permission denied",
+			},
+		},
+		{
+			"Permission denied",
+			ErrorDetails{Cause: "Permission denied"},
+		},
+	}
+
+	for _, c := range cases {
+		details := ParseErrorMessage(c.msg)
+		if !reflect.DeepEqual(details, c.expected) {
+			t.Errorf("Parsing %q\n got: %+v\nwant: %+v", c.msg, details, c.expected)
+		}
+	}
+}
+
+func TestParseError(t *testing.T) {
+	err := newOpError("stat", gErrorImpl{
+		domain:  "http_plugin",
+		code:    syscall.ENOENT,
+		message: "[gfal2_stat][gfal_plugin_statG] File not found",
+	}, "https://host/path")
+
+	details := ParseError(err)
+	if details.Code != syscall.ENOENT || details.Domain != "http_plugin" || details.Plugin != "http" {
+		t.Error("Unexpected details: ", details)
+	}
+	if details.Cause != "File not found" {
+		t.Error("Unexpected cause: ", details.Cause)
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"gitlab.cern.ch/dmc/go-gfal2"
+	"io"
 	"os"
 )
 
@@ -27,14 +28,7 @@ func runCat(context *gfal2.Context, cmd *Command, args []string) int {
 	}
 	defer fd.Close()
 
-	buffer := make([]byte, 1024)
-	nBytes, err := fd.Read(buffer)
-
-	for ; nBytes > 0 && err == nil; nBytes, err = fd.Read(buffer) {
-		os.Stdout.Write(buffer[:nBytes])
-	}
-
-	if err != nil {
+	if _, err := io.Copy(os.Stdout, fd); err != nil {
 		Log("MAIN", gfal2.LogLevelCritical, "Could not read the file: %s", err.Error())
 		return -1
 	}
@@ -16,10 +16,6 @@ func init() {
 
 var fullList = cmdLs.Flag.Bool("l", false, "long list format")
 
-func printShort(info gfal2.Stat) {
-	fmt.Printf("%s\n", info.Name())
-}
-
 func printLong(info gfal2.Stat) {
 	fmt.Printf("%s %#5d %#6d %#6d %#6d %s %s\n", info.Mode().String(), info.Nlink(), info.Uid(), info.Gid(), info.Size(), info.ModTime().String(), info.Name())
 }
@@ -30,24 +26,30 @@ func runLs(context *gfal2.Context, cmd *Command, args []string) int {
 		return -1
 	}
 
+	if !*fullList {
+		names, err := context.ReadDirNames(args[0])
+		if err != nil {
+			Log("MAIN", gfal2.LogLevelCritical, "Could not read the directory: %s", err.Error())
+			return -1
+		}
+		for _, name := range names {
+			fmt.Printf("%s\n", name)
+		}
+		return 0
+	}
+
 	dir, err := context.Opendir(args[0])
 	if err != nil {
 		Log("MAIN", gfal2.LogLevelCritical, "Could not open the directory: %s", err.Error())
 		return -1
 	}
-	defer dir.Close()
-
-	info, err := dir.Readdir()
-	for ; info != nil && err == nil; info, err = dir.Readdir() {
-		if *fullList {
-			printLong(info)
-		} else {
-			printShort(info)
-		}
-	}
 
-	if err != nil {
-		Log("MAIN", gfal2.LogLevelCritical, "Could not read the directory: %s", err.Error())
+	for info, err := range dir.All() {
+		if err != nil {
+			Log("MAIN", gfal2.LogLevelCritical, "Could not read the directory: %s", err.Error())
+			break
+		}
+		printLong(info)
 	}
 
 	return 0
@@ -20,14 +20,20 @@ package gfal2
 // #include <gfal_api.h>
 import "C"
 import (
+	"io"
 	"os"
+	"syscall"
 	"unsafe"
 )
 
+// Size of the buffer used by ReadFrom and WriteTo.
+const copyBufferSize = 4 * 1024 * 1024
+
 // File contains the required data to operate on a file.
 type File struct {
 	cFd      C.int
 	cContext C.gfal2_context_t
+	url      string
 }
 
 // Open a file in read only mode.
@@ -46,21 +52,22 @@ func (context Context) OpenFile(url string, flag int, perm os.FileMode) (*File,
 
 	var fd File
 	fd.cContext = context.cContext
+	fd.url = url
 	fd.cFd = C.gfal2_open2(context.cContext, cURL, C.int(flag), C.mode_t(perm), &err)
 	if fd.cFd < 0 {
-		return nil, errorCtoGo(err)
+		return nil, opErrorCtoGo("open", err, url)
 	}
 
 	return &fd, nil
 }
 
 // Close a file and frees the associated memory.
-func (fd File) Close() GError {
+func (fd File) Close() error {
 	var err *C.GError
 
 	ret := C.gfal2_close(fd.cContext, fd.cFd, &err)
 	if ret < 0 {
-		return errorCtoGo(err)
+		return opErrorCtoGo("close", err, fd.url)
 	}
 
 	return nil
@@ -71,61 +78,200 @@ func (context Context) Create(url string) (*File, GError) {
 	return context.OpenFile(url, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0777)
 }
 
-// Read reads up to len(b) bytes from the Gfal2File.
-// It returns the number of bytes read and an error, if any.
-// EOF is signaled by a zero count.
-// On error the count is negative.
-func (fd File) Read(b []byte) (int, GError) {
+// Read a single chunk into b, as returned by gfal2_read. 0 means EOF.
+func (fd File) read(b []byte) (int, GError) {
 	var err *C.GError
 
 	bufferPtr := (*C.void)(unsafe.Pointer(&b[0]))
 
 	ret := C.gfal2_read(fd.cContext, fd.cFd, unsafe.Pointer(bufferPtr), C.size_t(len(b)), &err)
 	if ret < 0 {
-		return -1, errorCtoGo(err)
+		return 0, opErrorCtoGo("read", err, fd.url)
 	}
 
 	return int(ret), nil
 }
 
-// Write len(b) bytes from b into the Gfal2File.
-// It returns the number of bytes written and an error, if any.
-// On error the count is negative.
-func (fd File) Write(b []byte) (int, GError) {
+// Write b into the file until all of it has been written, or an error happens.
+func (fd File) write(b []byte) (int, GError) {
 	var err *C.GError
 
-	bufferPtr := (*C.void)(unsafe.Pointer(&b[0]))
+	written := 0
+	for written < len(b) {
+		bufferPtr := (*C.void)(unsafe.Pointer(&b[written]))
 
-	ret := C.gfal2_write(fd.cContext, fd.cFd, unsafe.Pointer(bufferPtr), C.size_t(len(b)), &err)
-	if ret < 0 {
-		return -1, errorCtoGo(err)
+		ret := C.gfal2_write(fd.cContext, fd.cFd, unsafe.Pointer(bufferPtr), C.size_t(len(b)-written), &err)
+		if ret < 0 {
+			return written, opErrorCtoGo("write", err, fd.url)
+		} else if ret == 0 {
+			return written, newOpError("write", &gErrorImpl{code: syscall.EIO, message: io.ErrShortWrite.Error()}, fd.url)
+		}
+		written += int(ret)
 	}
 
-	return int(ret), nil
+	return written, nil
+}
+
+// Read reads up to len(b) bytes from the Gfal2File.
+// It returns the number of bytes read and an error, if any.
+// At end of file, Read returns 0, io.EOF.
+func (fd File) Read(b []byte) (int, error) {
+	if len(b) == 0 {
+		return 0, nil
+	}
+
+	n, err := fd.read(b)
+	if err != nil {
+		return 0, err
+	}
+	if n == 0 {
+		return 0, io.EOF
+	}
+
+	return n, nil
+}
+
+// Write len(b) bytes from b into the Gfal2File.
+// It returns the number of bytes written and an error, if any.
+// Write returns a non-nil error when n != len(b).
+func (fd File) Write(b []byte) (int, error) {
+	if len(b) == 0 {
+		return 0, nil
+	}
+
+	n, err := fd.write(b)
+	if err != nil {
+		return n, err
+	}
+
+	return n, nil
 }
 
 // Seek changes the cursor position in the Gfal2File.
-// whence: 0 means relative to the origin of the file, 1 means relative to the current offset,
-// and 2 means relative to the end.
+// whence: io.SeekStart means relative to the origin of the file, io.SeekCurrent means relative
+// to the current offset, and io.SeekEnd means relative to the end.
 // It returns the new offset and an error, if any.
-func (fd File) Seek(offset int64, whence int) (int64, GError) {
+func (fd File) Seek(offset int64, whence int) (int64, error) {
 	var err *C.GError
 
 	ret := C.gfal2_lseek(fd.cContext, fd.cFd, C.off_t(offset), C.int(whence), &err)
 	if ret < 0 {
-		return -1, errorCtoGo(err)
+		return 0, opErrorCtoGo("seek", err, fd.url)
 	}
 
 	return int64(ret), nil
 }
 
+// ReadAt reads len(b) bytes from the file starting at byte offset off, using gfal2_pread.
+// It returns the number of bytes read and an error, if any. When n < len(b), the error is
+// not nil, and it is io.EOF if the end of the file was reached.
+// ReadAt does not use nor modify the file cursor, so it is safe to call it concurrently
+// from several goroutines.
+func (fd File) ReadAt(b []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, newOpError("pread", &gErrorImpl{code: syscall.EINVAL, message: "negative offset"}, fd.url)
+	}
+
+	var err *C.GError
+
+	read := 0
+	for read < len(b) {
+		bufferPtr := (*C.void)(unsafe.Pointer(&b[read]))
+
+		ret := C.gfal2_pread(fd.cContext, fd.cFd, unsafe.Pointer(bufferPtr), C.size_t(len(b)-read), C.off_t(off+int64(read)), &err)
+		if ret < 0 {
+			return read, opErrorCtoGo("pread", err, fd.url)
+		} else if ret == 0 {
+			return read, io.EOF
+		}
+		read += int(ret)
+	}
+
+	return read, nil
+}
+
+// WriteAt writes len(b) bytes into the file starting at byte offset off, using gfal2_pwrite.
+// It returns the number of bytes written and an error, if any. When n < len(b), the error is not nil.
+// WriteAt does not use nor modify the file cursor, so it is safe to call it concurrently
+// from several goroutines, as long as they write into different ranges.
+func (fd File) WriteAt(b []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, newOpError("pwrite", &gErrorImpl{code: syscall.EINVAL, message: "negative offset"}, fd.url)
+	}
+
+	var err *C.GError
+
+	written := 0
+	for written < len(b) {
+		bufferPtr := (*C.void)(unsafe.Pointer(&b[written]))
+
+		ret := C.gfal2_pwrite(fd.cContext, fd.cFd, unsafe.Pointer(bufferPtr), C.size_t(len(b)-written), C.off_t(off+int64(written)), &err)
+		if ret < 0 {
+			return written, opErrorCtoGo("pwrite", err, fd.url)
+		} else if ret == 0 {
+			return written, newOpError("pwrite", &gErrorImpl{code: syscall.EIO, message: io.ErrShortWrite.Error()}, fd.url)
+		}
+		written += int(ret)
+	}
+
+	return written, nil
+}
+
+// ReadFrom reads from r until EOF, writing into the file using a large buffer.
+// It implements io.ReaderFrom, so io.Copy into a File does not use small chunks.
+func (fd File) ReadFrom(r io.Reader) (int64, error) {
+	buffer := make([]byte, copyBufferSize)
+	var total int64
+
+	for {
+		nRead, readErr := r.Read(buffer)
+		if nRead > 0 {
+			nWritten, err := fd.write(buffer[:nRead])
+			total += int64(nWritten)
+			if err != nil {
+				return total, err
+			}
+		}
+		if readErr == io.EOF {
+			return total, nil
+		} else if readErr != nil {
+			return total, readErr
+		}
+	}
+}
+
+// WriteTo reads the file until EOF, writing into w using a large buffer.
+// It implements io.WriterTo, so io.Copy from a File does not use small chunks.
+func (fd File) WriteTo(w io.Writer) (int64, error) {
+	buffer := make([]byte, copyBufferSize)
+	var total int64
+
+	for {
+		nRead, err := fd.read(buffer)
+		if err != nil {
+			return total, err
+		}
+		if nRead == 0 {
+			return total, nil
+		}
+
+		nWritten, writeErr := w.Write(buffer[:nRead])
+		total += int64(nWritten)
+		if writeErr != nil {
+			return total, writeErr
+		} else if nWritten != nRead {
+			return total, io.ErrShortWrite
+		}
+	}
+}
+
 // Flush the Gfal2File.
 func (fd File) Flush() GError {
 	var err *C.GError
 
 	ret := C.gfal2_flush(fd.cContext, fd.cFd, &err)
 	if ret < 0 {
-		return errorCtoGo(err)
+		return opErrorCtoGo("flush", err, fd.url)
 	}
 
 	return nil
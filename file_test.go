@@ -0,0 +1,112 @@
+package gfal2
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func writeLocalFile(t *testing.T, size int) (string, []byte) {
+	content := make([]byte, size)
+	for i := range content {
+		content[i] = byte(i % 251)
+	}
+	path := filepath.Join(t.TempDir(), "data")
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return "file://" + path, content
+}
+
+func TestReadAtConcurrent(t *testing.T) {
+	context := getContext(t)
+	url, content := writeLocalFile(t, 1024*1024)
+
+	fd, err := context.Open(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fd.Close()
+
+	chunk := 64 * 1024
+	var wg sync.WaitGroup
+	for off := 0; off < len(content); off += chunk {
+		wg.Add(1)
+		go func(off int) {
+			defer wg.Done()
+			buffer := make([]byte, chunk)
+			n, err := fd.ReadAt(buffer, int64(off))
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if !bytes.Equal(buffer[:n], content[off:off+chunk]) {
+				t.Error("Unexpected content at offset ", off)
+			}
+		}(off)
+	}
+	wg.Wait()
+
+	buffer := make([]byte, 16)
+	n, rerr := fd.ReadAt(buffer, int64(len(content)-8))
+	if n != 8 || rerr != io.EOF {
+		t.Error("Was expecting 8 bytes and io.EOF, got ", n, rerr)
+	}
+}
+
+func TestFileReadWrite(t *testing.T) {
+	context := getContext(t)
+	_, content := writeLocalFile(t, 5*1024*1024+123)
+	url := "file://" + filepath.Join(t.TempDir(), "copy")
+
+	// io.Copy into a File goes through ReadFrom, as long as the source does not implement io.WriterTo
+	fd, err := context.Create(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, err := fd.Write(nil); n != 0 || err != nil {
+		t.Error("Was expecting an empty write, got ", n, err)
+	}
+	written, cerr := io.Copy(fd, struct{ io.Reader }{bytes.NewReader(content)})
+	if cerr != nil || written != int64(len(content)) {
+		t.Fatal("Unexpected copy into the file: ", written, cerr)
+	}
+	if cerr := fd.Close(); cerr != nil {
+		t.Fatal(cerr)
+	}
+
+	fd, err = context.Open(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fd.Close()
+
+	if n, err := fd.Read(nil); n != 0 || err != nil {
+		t.Error("Was expecting an empty read, got ", n, err)
+	}
+
+	// io.Copy from a File goes through WriteTo
+	var buffer bytes.Buffer
+	if _, err := io.Copy(&buffer, fd); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buffer.Bytes(), content) {
+		t.Error("Unexpected content")
+	}
+
+	if n, err := fd.Read(make([]byte, 16)); n != 0 || err != io.EOF {
+		t.Error("Was expecting io.EOF, got ", n, err)
+	}
+
+	// Plain reads, after rewinding
+	if offset, err := fd.Seek(0, io.SeekStart); offset != 0 || err != nil {
+		t.Fatal("Unexpected seek: ", offset, err)
+	}
+	all, rerr := io.ReadAll(struct{ io.Reader }{fd})
+	if rerr != nil || !bytes.Equal(all, content) {
+		t.Error("Unexpected content with plain reads: ", len(all), rerr)
+	}
+}
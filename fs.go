@@ -0,0 +1,246 @@
+/*
+ * Copyright (c) CERN 2016
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gfal2
+
+import (
+	"io"
+	"io/fs"
+	"sort"
+	"strings"
+	"syscall"
+)
+
+// FS exposes the namespace under a gfal2 url as a read-only fs.FS.
+// It implements fs.ReadDirFS, fs.StatFS, fs.ReadFileFS and fs.SubFS.
+type FS struct {
+	context *Context
+	baseURL string
+}
+
+// NewFS returns an fs.FS rooted at baseURL.
+// Names passed to its methods are slash separated paths relative to baseURL, as required by fs.ValidPath.
+func NewFS(ctx *Context, baseURL string) *FS {
+	return &FS{
+		context: ctx,
+		baseURL: strings.TrimRight(baseURL, "/"),
+	}
+}
+
+// URL returns the full url corresponding to name.
+func (fsys *FS) URL(name string) string {
+	if name == "." {
+		return fsys.baseURL
+	}
+	return fsys.baseURL + "/" + name
+}
+
+// fsFileInfo overrides the name of a Stat, so the root is called ".".
+type fsFileInfo struct {
+	Stat
+	name string
+}
+
+// Name returns the base name of the file.
+func (info fsFileInfo) Name() string {
+	return info.name
+}
+
+// Base name of name, as expected by fs.FileInfo.
+func fsBase(name string) string {
+	if i := strings.LastIndexByte(name, '/'); i >= 0 {
+		return name[i+1:]
+	}
+	return name
+}
+
+// Stat returns a fs.FileInfo describing the named file.
+func (fsys *FS) Stat(name string) (fs.FileInfo, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrInvalid}
+	}
+	info, err := fsys.context.Stat(fsys.URL(name))
+	if err != nil {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: err}
+	}
+	return fsFileInfo{Stat: info, name: fsBase(name)}, nil
+}
+
+// Open opens the named file or directory.
+func (fsys *FS) Open(name string) (fs.File, error) {
+	info, err := fsys.Stat(name)
+	if err != nil {
+		if pathErr, ok := err.(*fs.PathError); ok {
+			pathErr.Op = "open"
+		}
+		return nil, err
+	}
+
+	if info.IsDir() {
+		return &fsDir{fsys: fsys, name: name, info: info}, nil
+	}
+
+	fd, gerr := fsys.context.Open(fsys.URL(name))
+	if gerr != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: gerr}
+	}
+	return &fsFile{File: fd, name: name, info: info}, nil
+}
+
+// ReadDir reads the named directory and returns a list of directory entries sorted by filename.
+func (fsys *FS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+
+	dir := &fsDir{fsys: fsys, name: name}
+	entries, err := dir.ReadDir(-1)
+	if closeErr := dir.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+	return entries, nil
+}
+
+// ReadFile reads the named file and returns its contents.
+func (fsys *FS) ReadFile(name string) ([]byte, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readfile", Path: name, Err: fs.ErrInvalid}
+	}
+
+	fd, gerr := fsys.context.Open(fsys.URL(name))
+	if gerr != nil {
+		return nil, &fs.PathError{Op: "readfile", Path: name, Err: gerr}
+	}
+	defer fd.Close()
+
+	data, err := io.ReadAll(fd)
+	if err != nil {
+		return nil, &fs.PathError{Op: "readfile", Path: name, Err: err}
+	}
+	return data, nil
+}
+
+// Sub returns an FS rooted at the subdirectory dir.
+func (fsys *FS) Sub(dir string) (fs.FS, error) {
+	if !fs.ValidPath(dir) {
+		return nil, &fs.PathError{Op: "sub", Path: dir, Err: fs.ErrInvalid}
+	}
+	if dir == "." {
+		return fsys, nil
+	}
+	return NewFS(fsys.context, fsys.URL(dir)), nil
+}
+
+// fsFile is a regular file opened through FS.
+type fsFile struct {
+	*File
+	name string
+	info fs.FileInfo
+}
+
+// Stat returns the fs.FileInfo of the file, as it was when opened.
+func (file *fsFile) Stat() (fs.FileInfo, error) {
+	return file.info, nil
+}
+
+// fsDir is a directory opened through FS. It implements fs.ReadDirFile.
+type fsDir struct {
+	fsys *FS
+	name string
+	info fs.FileInfo
+	dir  *Dir
+	eof  bool
+}
+
+// Stat returns the fs.FileInfo of the directory, as it was when opened.
+func (file *fsDir) Stat() (fs.FileInfo, error) {
+	return file.info, nil
+}
+
+// Read fails, since file is a directory.
+func (file *fsDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: file.name, Err: syscall.EISDIR}
+}
+
+// Close closes the directory.
+func (file *fsDir) Close() error {
+	if file.dir == nil {
+		return nil
+	}
+	err := file.dir.Close()
+	file.dir = nil
+	if err != nil {
+		return &fs.PathError{Op: "close", Path: file.name, Err: err}
+	}
+	return nil
+}
+
+// ReadDir reads the contents of the directory, as specified by fs.ReadDirFile.
+// If n > 0, it returns at most n entries, and io.EOF at the end of the directory.
+// If n <= 0, it returns all the remaining entries.
+func (file *fsDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	if file.eof {
+		if n > 0 {
+			return nil, io.EOF
+		}
+		return []fs.DirEntry{}, nil
+	}
+
+	if file.dir == nil {
+		dir, err := file.fsys.context.Opendir(file.fsys.URL(file.name))
+		if err != nil {
+			return nil, &fs.PathError{Op: "readdir", Path: file.name, Err: err}
+		}
+		file.dir = dir
+	}
+
+	entries := []fs.DirEntry{}
+	for n <= 0 || len(entries) < n {
+		info, err := file.dir.Readdir()
+		if err != nil {
+			return entries, &fs.PathError{Op: "readdir", Path: file.name, Err: err}
+		}
+		if info == nil {
+			file.eof = true
+			break
+		}
+		if name := info.Name(); name == "." || name == ".." {
+			continue
+		}
+		entries = append(entries, fs.FileInfoToDirEntry(info))
+	}
+
+	if n > 0 && len(entries) == 0 {
+		return nil, io.EOF
+	}
+	return entries, nil
+}
+
+// Make sure FS implements the expected interfaces.
+var (
+	_ fs.ReadDirFS   = (*FS)(nil)
+	_ fs.StatFS      = (*FS)(nil)
+	_ fs.ReadFileFS  = (*FS)(nil)
+	_ fs.SubFS       = (*FS)(nil)
+	_ fs.ReadDirFile = (*fsDir)(nil)
+)
@@ -0,0 +1,35 @@
+package gfal2
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+func TestFS(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"a.txt":           "hello",
+		"dir/b.txt":       "world",
+		"dir/sub/c.txt":   "nested content",
+		"dir/sub/d/e.bin": "\x00\x01\x02",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(root, "empty"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	fsys := NewFS(getContext(t), "file://"+root)
+	if err := fstest.TestFS(fsys, "a.txt", "dir/b.txt", "dir/sub/c.txt", "dir/sub/d/e.bin", "empty"); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -20,6 +20,8 @@ package gfal2
 // #include <gfal_api.h>
 import "C"
 import (
+	"errors"
+	"os"
 	"syscall"
 )
 
@@ -54,12 +56,136 @@ func (e gErrorImpl) Error() string {
 	return e.message
 }
 
+// Unwrap returns the error code as a syscall.Errno, so errors.Is and errors.As can be used
+// with errno values.
+func (e gErrorImpl) Unwrap() error {
+	return e.code
+}
+
+// Is reports whether the error matches target. Besides the errno values, it matches
+// fs.ErrNotExist, fs.ErrPermission, fs.ErrExist and fs.ErrUnsupported like a syscall.Errno does,
+// and os.ErrDeadlineExceeded for timeouts.
+func (e gErrorImpl) Is(target error) bool {
+	if target == os.ErrDeadlineExceeded {
+		return e.code == syscall.ETIMEDOUT
+	}
+	return e.code.Is(target)
+}
+
+// Error codes that are likely to go away if the operation is retried.
+var transientCodes = map[syscall.Errno]bool{
+	syscall.EAGAIN:       true,
+	syscall.EBUSY:        true,
+	syscall.ETIMEDOUT:    true,
+	syscall.ECOMM:        true,
+	syscall.EHOSTUNREACH: true,
+	syscall.EHOSTDOWN:    true,
+	syscall.ENETUNREACH:  true,
+	syscall.ENETDOWN:     true,
+	syscall.ENETRESET:    true,
+	syscall.ECONNRESET:   true,
+	syscall.ECONNREFUSED: true,
+	syscall.ECONNABORTED: true,
+	syscall.EPIPE:        true,
+	syscall.EPROTO:       true,
+	syscall.ENOLINK:      true,
+}
+
+// errorCode extracts the error code from a GError or a syscall.Errno, wrapped or not.
+func errorCode(err error) (syscall.Errno, bool) {
+	var gerr GError
+	if errors.As(err, &gerr) {
+		return gerr.Code(), true
+	}
+	var errno syscall.Errno
+	if errors.As(err, &errno) {
+		return errno, true
+	}
+	return 0, false
+}
+
+// IsTransient returns true if err is likely to go away if the operation is retried.
+// For instance, EAGAIN, ETIMEDOUT, ECOMM, EHOSTUNREACH or a connection reset.
+func IsTransient(err error) bool {
+	code, ok := errorCode(err)
+	return ok && transientCodes[code]
+}
+
+// IsPermanent returns true if err is not nil, and retrying the operation will not help.
+// For instance, ENOENT, EACCES, EEXIST or a cancelled operation.
+func IsPermanent(err error) bool {
+	return err != nil && !IsTransient(err)
+}
+
+// OpError records the operation, and the url or urls, that caused a GError.
+// It implements GError itself, and unwraps to the underlying one.
+type OpError struct {
+	// Operation that failed, as "stat", "copy" or "bring_online".
+	Op string
+	// Url the operation was working on. It may be empty for operations that do not involve any.
+	URL string
+	// Second url, for operations that involve two, as "copy", "rename" or "symlink".
+	Destination string
+	// The error returned by gfal2.
+	Err GError
+}
+
+// Domain returns the domain of the underlying error.
+func (e *OpError) Domain() string {
+	return e.Err.Domain()
+}
+
+// Code returns the error code of the underlying error.
+func (e *OpError) Code() syscall.Errno {
+	return e.Err.Code()
+}
+
+// Error returns the error message, prefixed by the operation and urls.
+func (e *OpError) Error() string {
+	switch {
+	case e.Destination != "":
+		return e.Op + " " + e.URL + " => " + e.Destination + ": " + e.Err.Error()
+	case e.URL != "":
+		return e.Op + " " + e.URL + ": " + e.Err.Error()
+	default:
+		return e.Op + ": " + e.Err.Error()
+	}
+}
+
+// Unwrap returns the underlying error.
+func (e *OpError) Unwrap() error {
+	return e.Err
+}
+
+// Wrap err into an OpError. urls are the url and, optionally, the destination.
+// Returns nil if err is nil.
+func newOpError(op string, err GError, urls ...string) GError {
+	if err == nil {
+		return nil
+	}
+	opErr := &OpError{Op: op, Err: err}
+	if len(urls) > 0 {
+		opErr.URL = urls[0]
+	}
+	if len(urls) > 1 {
+		opErr.Destination = urls[1]
+	}
+	return opErr
+}
+
 // Convert a C GError to a Go GError.
 // Frees the C error .
 func errorCtoGo(e *C.GError) gErrorImpl {
 	var err gErrorImpl
+	err.domain = C.GoString((*C.char)(C.g_quark_to_string(e.domain)))
 	err.code = syscall.Errno(e.code)
 	err.message = C.GoString((*C.char)(e.message))
 	C.g_clear_error(&e)
 	return err
 }
+
+// Convert a C GError to an OpError for the operation op on urls.
+// Frees the C error.
+func opErrorCtoGo(op string, e *C.GError, urls ...string) GError {
+	return newOpError(op, errorCtoGo(e), urls...)
+}
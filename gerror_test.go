@@ -0,0 +1,121 @@
+package gfal2
+
+import (
+	gocontext "context"
+	"errors"
+	"io/fs"
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestErrorIs(t *testing.T) {
+	var err error = gErrorImpl{code: syscall.ENOENT, message: "not found"}
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Error("ENOENT should match fs.ErrNotExist")
+	}
+	if !errors.Is(err, syscall.ENOENT) {
+		t.Error("ENOENT should match syscall.ENOENT")
+	}
+	if errors.Is(err, fs.ErrPermission) {
+		t.Error("ENOENT should not match fs.ErrPermission")
+	}
+
+	err = gErrorImpl{code: syscall.EACCES}
+	if !errors.Is(err, fs.ErrPermission) {
+		t.Error("EACCES should match fs.ErrPermission")
+	}
+
+	err = gErrorImpl{code: syscall.EEXIST}
+	if !errors.Is(err, fs.ErrExist) {
+		t.Error("EEXIST should match fs.ErrExist")
+	}
+
+	err = gErrorImpl{code: syscall.ETIMEDOUT}
+	if !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Error("ETIMEDOUT should match os.ErrDeadlineExceeded")
+	}
+}
+
+func TestContextErrorIs(t *testing.T) {
+	var err error = newContextError(gocontext.Canceled, nil)
+	if !errors.Is(err, gocontext.Canceled) || !errors.Is(err, syscall.ECANCELED) {
+		t.Error("A cancelled operation should match context.Canceled and ECANCELED: ", err)
+	}
+
+	err = newContextError(gocontext.DeadlineExceeded, nil)
+	if !errors.Is(err, gocontext.DeadlineExceeded) || !errors.Is(err, syscall.ETIMEDOUT) {
+		t.Error("A timed out operation should match context.DeadlineExceeded and ETIMEDOUT: ", err)
+	}
+	if errors.Is(err, syscall.ECANCELED) {
+		t.Error("A timed out operation should not match ECANCELED")
+	}
+}
+
+func TestErrorClassification(t *testing.T) {
+	transient := []syscall.Errno{syscall.EAGAIN, syscall.ETIMEDOUT, syscall.ECOMM, syscall.EHOSTUNREACH}
+	for _, code := range transient {
+		err := gErrorImpl{code: code}
+		if !IsTransient(err) || IsPermanent(err) {
+			t.Error("Was expecting a transient error for ", code)
+		}
+	}
+
+	permanent := []syscall.Errno{syscall.ENOENT, syscall.EACCES, syscall.EEXIST, syscall.ECANCELED}
+	for _, code := range permanent {
+		err := gErrorImpl{code: code}
+		if IsTransient(err) || !IsPermanent(err) {
+			t.Error("Was expecting a permanent error for ", code)
+		}
+	}
+
+	if IsPermanent(nil) || IsTransient(nil) {
+		t.Error("nil is neither transient nor permanent")
+	}
+}
+
+func TestOpError(t *testing.T) {
+	err := newOpError("copy", gErrorImpl{domain: "http_plugin", code: syscall.ENOENT, message: "no such file"},
+		"https://host/src", "https://host/dst")
+
+	if err.Error() != "copy https://host/src => https://host/dst: no such file" {
+		t.Error("Unexpected message: ", err.Error())
+	}
+	if err.Domain() != "http_plugin" || err.Code() != syscall.ENOENT {
+		t.Error("Unexpected domain or code: ", err.Domain(), err.Code())
+	}
+
+	var opErr *OpError
+	if !errors.As(err, &opErr) {
+		t.Fatal("Was expecting an OpError")
+	}
+	if opErr.Op != "copy" || opErr.URL != "https://host/src" || opErr.Destination != "https://host/dst" {
+		t.Error("Unexpected OpError: ", opErr)
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Error("OpError should unwrap to the underlying error")
+	}
+
+	if newOpError("stat", nil, "https://host/path") != nil {
+		t.Error("Wrapping nil should return nil")
+	}
+}
+
+func TestContextErrorKeepsOpError(t *testing.T) {
+	aborted := newOpError("stat", gErrorImpl{domain: "http_plugin", code: syscall.ECANCELED, message: "[gfal2_stat] Operation canceled"},
+		"https://host/path")
+	var err error = newContextError(gocontext.Canceled, aborted)
+
+	var opErr *OpError
+	if !errors.As(err, &opErr) || opErr.Op != "stat" || opErr.URL != "https://host/path" {
+		t.Error("The OpError should be reachable with errors.As: ", err)
+	}
+	if !errors.Is(err, gocontext.Canceled) || !errors.Is(err, syscall.ECANCELED) {
+		t.Error("Unexpected chain: ", err)
+	}
+
+	details := ParseError(err)
+	if details.Cause != "Operation canceled" || len(details.Functions) != 1 || details.Plugin != "http" {
+		t.Error("Unexpected details: ", details)
+	}
+}
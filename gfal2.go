@@ -47,7 +47,7 @@ func NewContext() (*Context, GError) {
 	context.cContext = C.gfal2_context_new(&err)
 
 	if context.cContext == nil {
-		return nil, errorCtoGo(err)
+		return nil, opErrorCtoGo("context_new", err)
 	}
 
 	return &context, nil
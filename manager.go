@@ -0,0 +1,319 @@
+/*
+ * Copyright (c) CERN 2016
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gfal2
+
+import (
+	gocontext "context"
+	neturl "net/url"
+	"sync"
+)
+
+// JobState is the state of a TransferJob.
+type JobState int
+
+// Job states.
+const (
+	JobQueued JobState = iota
+	JobActive
+	JobDone
+	JobFailed
+)
+
+// String returns the name of the state.
+func (state JobState) String() string {
+	switch state {
+	case JobQueued:
+		return "queued"
+	case JobActive:
+		return "active"
+	case JobDone:
+		return "done"
+	case JobFailed:
+		return "failed"
+	}
+	return "unknown"
+}
+
+// TransferManagerOptions configures a TransferManager.
+type TransferManagerOptions struct {
+	// Maximum number of transfers running at the same time. Defaults to 10.
+	MaxActive int
+	// Maximum number of transfers running at the same time from a given source host. 0 means no limit.
+	MaxPerSource int
+	// Maximum number of transfers running at the same time to a given destination host. 0 means no limit.
+	MaxPerDestination int
+	// If set, it is called for every job before the copy starts, so the context and transfer handler
+	// can be configured (overwrite, checksum, timeout...). If it fails, the job fails.
+	Configure func(job *TransferJob, context *Context, handler *TransferHandler) error
+}
+
+// TransferJob is a copy submitted to a TransferManager.
+type TransferJob struct {
+	Source      string
+	Destination string
+
+	manager *TransferManager
+	ctx     gocontext.Context
+	cancel  gocontext.CancelFunc
+	done    chan struct{}
+
+	mutex   sync.Mutex
+	state   JobState
+	err     error
+	markers []Marker
+	events  []Event
+}
+
+// State returns the current state of the job.
+func (job *TransferJob) State() JobState {
+	job.mutex.Lock()
+	defer job.mutex.Unlock()
+	return job.state
+}
+
+// Err returns the error of a failed job, nil otherwise.
+// The error of a cancelled job matches context.Canceled.
+func (job *TransferJob) Err() error {
+	job.mutex.Lock()
+	defer job.mutex.Unlock()
+	return job.err
+}
+
+// Markers returns the performance markers received so far.
+func (job *TransferJob) Markers() []Marker {
+	job.mutex.Lock()
+	defer job.mutex.Unlock()
+	return append([]Marker(nil), job.markers...)
+}
+
+// Events returns the events received so far.
+func (job *TransferJob) Events() []Event {
+	job.mutex.Lock()
+	defer job.mutex.Unlock()
+	return append([]Event(nil), job.events...)
+}
+
+// Done returns a channel that is closed when the job is done or failed.
+func (job *TransferJob) Done() <-chan struct{} {
+	return job.done
+}
+
+// Wait blocks until the job finishes, and returns its error.
+func (job *TransferJob) Wait() error {
+	<-job.done
+	return job.Err()
+}
+
+// Cancel the job. A queued job is removed from the queue, and an active one is interrupted.
+// Either way, it ends up failed.
+func (job *TransferJob) Cancel() {
+	job.cancel()
+	job.manager.dequeue(job)
+}
+
+// NotifyPerformanceMarker implements MonitorListener.
+func (job *TransferJob) NotifyPerformanceMarker(marker Marker) {
+	job.mutex.Lock()
+	defer job.mutex.Unlock()
+	job.markers = append(job.markers, marker)
+}
+
+// NotifyEvent implements EventListener.
+func (job *TransferJob) NotifyEvent(event Event) {
+	job.mutex.Lock()
+	defer job.mutex.Unlock()
+	job.events = append(job.events, event)
+}
+
+// Mark the job as finished.
+func (job *TransferJob) finish(err error) {
+	job.mutex.Lock()
+	if err != nil {
+		job.state = JobFailed
+		job.err = err
+	} else {
+		job.state = JobDone
+	}
+	job.mutex.Unlock()
+	job.cancel()
+	close(job.done)
+}
+
+// TransferManager runs copy jobs concurrently, each with its own gfal2 context and TransferHandler,
+// within global and per host limits. Jobs start in the order they are submitted, unless the limits
+// of their hosts are reached, in which case the following ones can go first.
+type TransferManager struct {
+	options TransferManagerOptions
+
+	mutex          sync.Mutex
+	jobs           []*TransferJob
+	queue          []*TransferJob
+	active         int
+	perSource      map[string]int
+	perDestination map[string]int
+	wg             sync.WaitGroup
+}
+
+// NewTransferManager creates a new TransferManager. opts can be nil.
+func NewTransferManager(opts *TransferManagerOptions) *TransferManager {
+	var options TransferManagerOptions
+	if opts != nil {
+		options = *opts
+	}
+	if options.MaxActive <= 0 {
+		options.MaxActive = 10
+	}
+	return &TransferManager{
+		options:        options,
+		perSource:      make(map[string]int),
+		perDestination: make(map[string]int),
+	}
+}
+
+// Returns the host part of url, used to apply the per host limits.
+// Urls without a host, as all the file:// ones, share the empty host "", so they count against the same limits.
+func hostOf(url string) string {
+	parsed, err := neturl.Parse(url)
+	if err != nil {
+		return ""
+	}
+	return parsed.Host
+}
+
+// Submit queues a copy from source to destination, and returns the job.
+// The job is cancelled if ctx is done before it finishes.
+func (manager *TransferManager) Submit(ctx gocontext.Context, source string, destination string) *TransferJob {
+	job := &TransferJob{
+		Source:      source,
+		Destination: destination,
+		manager:     manager,
+		done:        make(chan struct{}),
+		state:       JobQueued,
+	}
+	job.ctx, job.cancel = gocontext.WithCancel(ctx)
+
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+	manager.jobs = append(manager.jobs, job)
+	manager.queue = append(manager.queue, job)
+	manager.wg.Add(1)
+	gocontext.AfterFunc(job.ctx, func() {
+		manager.dequeue(job)
+	})
+	manager.schedule()
+	return job
+}
+
+// Jobs returns all the submitted jobs, in order.
+func (manager *TransferManager) Jobs() []*TransferJob {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+	return append([]*TransferJob(nil), manager.jobs...)
+}
+
+// Wait blocks until all the submitted jobs finish.
+func (manager *TransferManager) Wait() {
+	manager.wg.Wait()
+}
+
+// Remove a cancelled job from the queue, if it is still there.
+func (manager *TransferManager) dequeue(job *TransferJob) {
+	manager.mutex.Lock()
+	found := false
+	for i, queued := range manager.queue {
+		if queued == job {
+			manager.queue = append(manager.queue[:i], manager.queue[i+1:]...)
+			found = true
+			break
+		}
+	}
+	manager.mutex.Unlock()
+
+	if found {
+		job.finish(newContextError(job.ctx.Err(), nil))
+		manager.wg.Done()
+	}
+}
+
+// Start as many queued jobs as the limits allow. Must be called with the lock held.
+func (manager *TransferManager) schedule() {
+	remaining := manager.queue[:0]
+	for _, job := range manager.queue {
+		source, destination := hostOf(job.Source), hostOf(job.Destination)
+		if manager.active >= manager.options.MaxActive ||
+			(manager.options.MaxPerSource > 0 && manager.perSource[source] >= manager.options.MaxPerSource) ||
+			(manager.options.MaxPerDestination > 0 && manager.perDestination[destination] >= manager.options.MaxPerDestination) {
+			remaining = append(remaining, job)
+			continue
+		}
+
+		manager.active++
+		manager.perSource[source]++
+		manager.perDestination[destination]++
+		job.mutex.Lock()
+		job.state = JobActive
+		job.mutex.Unlock()
+		go manager.run(job, source, destination)
+	}
+	clear(manager.queue[len(remaining):])
+	manager.queue = remaining
+}
+
+// Run a job, and release its slot afterwards.
+func (manager *TransferManager) run(job *TransferJob, source string, destination string) {
+	job.finish(manager.copy(job))
+
+	manager.mutex.Lock()
+	manager.active--
+	manager.perSource[source]--
+	manager.perDestination[destination]--
+	manager.schedule()
+	manager.mutex.Unlock()
+	manager.wg.Done()
+}
+
+// Copy the file of a job with a new context and handler.
+func (manager *TransferManager) copy(job *TransferJob) error {
+	context, gerr := NewContext()
+	if gerr != nil {
+		return gerr
+	}
+	defer context.Close()
+
+	handler, gerr := context.NewTransferHandler()
+	if gerr != nil {
+		return gerr
+	}
+	defer handler.Close()
+
+	if gerr := handler.AddMonitorCallback(job); gerr != nil {
+		return gerr
+	}
+	if gerr := handler.AddEventCallback(job); gerr != nil {
+		return gerr
+	}
+	if manager.options.Configure != nil {
+		if err := manager.options.Configure(job, context, handler); err != nil {
+			return err
+		}
+	}
+
+	if gerr := handler.CopyFileContext(job.ctx, job.Source, job.Destination); gerr != nil {
+		return gerr
+	}
+	return nil
+}
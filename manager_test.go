@@ -0,0 +1,121 @@
+package gfal2
+
+import (
+	"bytes"
+	gocontext "context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTransferManager(t *testing.T) {
+	source, content := writeLocalFile(t, 256*1024)
+	dir := t.TempDir()
+
+	manager := NewTransferManager(&TransferManagerOptions{MaxActive: 4, MaxPerSource: 2})
+	var jobs []*TransferJob
+	for i := 0; i < 8; i++ {
+		destination := fmt.Sprintf("file://%s/copy%d", dir, i)
+		jobs = append(jobs, manager.Submit(gocontext.Background(), source, destination))
+	}
+
+	cancelled := manager.Submit(gocontext.Background(), source, "file://"+dir+"/cancelled")
+	cancelled.Cancel()
+
+	manager.Wait()
+
+	for i, job := range jobs {
+		if err := job.Err(); err != nil || job.State() != JobDone {
+			t.Error("Job ", i, " failed: ", job.State(), err)
+			continue
+		}
+		copied, err := os.ReadFile(filepath.Join(dir, fmt.Sprintf("copy%d", i)))
+		if err != nil || !bytes.Equal(copied, content) {
+			t.Error("Unexpected content for job ", i, err)
+		}
+	}
+
+	if cancelled.State() != JobFailed || !errors.Is(cancelled.Err(), gocontext.Canceled) {
+		t.Error("Was expecting a cancelled job, got ", cancelled.State(), cancelled.Err())
+	}
+	if len(manager.Jobs()) != 9 {
+		t.Error("Was expecting 9 jobs, got ", len(manager.Jobs()))
+	}
+}
+
+// Counts the jobs running at the same time, in total and per host.
+type activeCounter struct {
+	mutex   sync.Mutex
+	current map[string]int
+	peak    map[string]int
+}
+
+func (counter *activeCounter) add(key string, delta int) {
+	counter.mutex.Lock()
+	defer counter.mutex.Unlock()
+	counter.current[key] += delta
+	if counter.current[key] > counter.peak[key] {
+		counter.peak[key] = counter.current[key]
+	}
+}
+
+func TestTransferManagerLimits(t *testing.T) {
+	errSkip := errors.New("skip")
+	counter := &activeCounter{current: make(map[string]int), peak: make(map[string]int)}
+
+	// Jobs hold their slot while Configure runs, so the counts never exceed the active ones.
+	// Configure fails, so no copy is attempted.
+	manager := NewTransferManager(&TransferManagerOptions{
+		MaxActive:         4,
+		MaxPerSource:      2,
+		MaxPerDestination: 1,
+		Configure: func(job *TransferJob, context *Context, handler *TransferHandler) error {
+			keys := []string{"total", "source " + hostOf(job.Source), "destination " + hostOf(job.Destination)}
+			for _, key := range keys {
+				counter.add(key, 1)
+			}
+			time.Sleep(20 * time.Millisecond)
+			for _, key := range keys {
+				counter.add(key, -1)
+			}
+			return errSkip
+		},
+	})
+
+	var jobs []*TransferJob
+	for i := 0; i < 12; i++ {
+		source := fmt.Sprintf("mock://source%d/file%d", i%2, i)
+		destination := fmt.Sprintf("mock://destination%d/file%d", i%3, i)
+		jobs = append(jobs, manager.Submit(gocontext.Background(), source, destination))
+	}
+	manager.Wait()
+
+	for i, job := range jobs {
+		if !errors.Is(job.Err(), errSkip) {
+			t.Error("Job ", i, " was not configured: ", job.Err())
+		}
+	}
+
+	counter.mutex.Lock()
+	defer counter.mutex.Unlock()
+	for key, peak := range counter.peak {
+		limit := 4
+		switch {
+		case strings.HasPrefix(key, "source "):
+			limit = 2
+		case strings.HasPrefix(key, "destination "):
+			limit = 1
+		}
+		if peak > limit {
+			t.Errorf("%d jobs active for %s, the limit is %d", peak, key, limit)
+		}
+	}
+	if counter.peak["total"] < 2 {
+		t.Error("The jobs did not run concurrently")
+	}
+}
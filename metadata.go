@@ -142,7 +142,7 @@ func (context Context) Checksum(url string, chktype string, offset uint64, lengt
 
 	ret := C.gfal2_checksum(context.cContext, cURL, cType, C.off_t(offset), C.size_t(length), bufferPtr, C.size_t(len(buffer)), &err)
 	if ret < 0 {
-		return "", errorCtoGo(err)
+		return "", opErrorCtoGo("checksum", err, url)
 	}
 
 	n := bytes.IndexByte(buffer, 0)
@@ -159,7 +159,7 @@ func (context Context) Access(url string, mode int) GError {
 
 	ret := C.gfal2_access(context.cContext, cURL, C.int(mode), &err)
 	if ret < 0 {
-		return errorCtoGo(err)
+		return opErrorCtoGo("access", err, url)
 	}
 
 	return nil
@@ -174,7 +174,7 @@ func (context Context) Chmod(url string, mode os.FileMode) GError {
 
 	ret := C.gfal2_chmod(context.cContext, cURL, C.mode_t(mode), &err)
 	if ret < 0 {
-		return errorCtoGo(err)
+		return opErrorCtoGo("chmod", err, url)
 	}
 
 	return nil
@@ -191,7 +191,7 @@ func (context Context) Rename(oldName string, newName string) GError {
 
 	ret := C.gfal2_rename(context.cContext, cOld, cNew, &err)
 	if ret < 0 {
-		return errorCtoGo(err)
+		return opErrorCtoGo("rename", err, oldName, newName)
 	}
 
 	return nil
@@ -207,7 +207,7 @@ func (context Context) Stat(url string) (Stat, GError) {
 	var stat StatAndName
 	ret := C.gfal2_stat(context.cContext, cURL, &stat.stat, &err)
 	if ret < 0 {
-		return nil, errorCtoGo(err)
+		return nil, opErrorCtoGo("stat", err, url)
 	}
 
 	stat.name = path.Base(url)
@@ -225,7 +225,7 @@ func (context Context) Lstat(url string) (Stat, GError) {
 	var stat StatAndName
 	ret := C.gfal2_lstat(context.cContext, cURL, &stat.stat, &err)
 	if ret < 0 {
-		return nil, errorCtoGo(err)
+		return nil, opErrorCtoGo("lstat", err, url)
 	}
 
 	stat.name = path.Base(url)
@@ -242,7 +242,7 @@ func (context Context) Mkdir(url string, mode os.FileMode) GError {
 
 	ret := C.gfal2_mkdir(context.cContext, cURL, C.mode_t(mode), &err)
 	if ret < 0 {
-		return errorCtoGo(err)
+		return opErrorCtoGo("mkdir", err, url)
 	}
 
 	return nil
@@ -257,7 +257,7 @@ func (context Context) MkdirAll(url string, mode os.FileMode) GError {
 
 	ret := C.gfal2_mkdir_rec(context.cContext, cURL, C.mode_t(mode), &err)
 	if ret < 0 {
-		return errorCtoGo(err)
+		return opErrorCtoGo("mkdir_rec", err, url)
 	}
 
 	return nil
@@ -270,19 +270,68 @@ func (context Context) Remove(url string) GError {
 		return gerr
 	}
 
+	if info.IsDir() {
+		return context.rmdir(url)
+	}
+	return context.unlink(url)
+}
+
+// RemoveList deletes a list of files in one go. Protocols that support bulk deletion (i.e. SRM or HTTP)
+// do it with a single request.
+// Unlike Remove, the urls are not stat'ed first, so they must point to files.
+// Return a list of errors, one per url. The error will be nil if the file was removed.
+func (context Context) RemoveList(urls []string) []GError {
+	nItems := len(urls)
+	errors := make([]GError, nItems)
+	if nItems == 0 {
+		return errors
+	}
+
+	cErrs := make([]*C.GError, nItems)
+	cUrls := make([]*C.char, nItems)
+
+	for i := 0; i < nItems; i++ {
+		cUrls[i] = (*C.char)(C.CString(urls[i]))
+	}
+
+	C.gfal2_unlink_list(context.cContext, C.int(nItems), (**C.char)(&cUrls[0]), &cErrs[0])
+
+	for i := 0; i < nItems; i++ {
+		C.free(unsafe.Pointer(cUrls[i]))
+		if cErrs[i] == nil {
+			errors[i] = nil
+		} else {
+			errors[i] = opErrorCtoGo("unlink", cErrs[i], urls[i])
+		}
+	}
+
+	return errors
+}
+
+// Remove a file, without checking first what it is.
+func (context Context) unlink(url string) GError {
+	var err *C.GError
+
 	cURL := (*C.char)(C.CString(url))
 	defer C.free(unsafe.Pointer(cURL))
 
-	var err *C.GError
-	var ret C.int
-	if info.IsDir() {
-		ret = C.gfal2_rmdir(context.cContext, cURL, &err)
-	} else {
-		ret = C.gfal2_unlink(context.cContext, cURL, &err)
+	ret := C.gfal2_unlink(context.cContext, cURL, &err)
+	if ret < 0 {
+		return opErrorCtoGo("unlink", err, url)
 	}
+	return nil
+}
+
+// Remove an empty directory, without checking first what it is.
+func (context Context) rmdir(url string) GError {
+	var err *C.GError
+
+	cURL := (*C.char)(C.CString(url))
+	defer C.free(unsafe.Pointer(cURL))
 
+	ret := C.gfal2_rmdir(context.cContext, cURL, &err)
 	if ret < 0 {
-		return errorCtoGo(err)
+		return opErrorCtoGo("rmdir", err, url)
 	}
 	return nil
 }
@@ -298,7 +347,7 @@ func (context Context) Symlink(source string, target string) GError {
 
 	ret := C.gfal2_symlink(context.cContext, cSource, cTarget, &err)
 	if ret < 0 {
-		return errorCtoGo(err)
+		return opErrorCtoGo("symlink", err, source, target)
 	}
 
 	return nil
@@ -316,7 +365,7 @@ func (context Context) Readlink(url string) (string, GError) {
 
 	ret := C.gfal2_readlink(context.cContext, cURL, bufferPtr, C.size_t(len(buffer)), &err)
 	if ret < 0 {
-		return "", errorCtoGo(err)
+		return "", opErrorCtoGo("readlink", err, url)
 	}
 
 	n := bytes.IndexByte(buffer, 0)
@@ -335,7 +384,7 @@ func (context Context) Listxattr(url string) ([]string, GError) {
 
 	ret := C.gfal2_listxattr(context.cContext, cURL, bufferPtr, C.size_t(len(buffer)), &err)
 	if ret < 0 {
-		return nil, errorCtoGo(err)
+		return nil, opErrorCtoGo("listxattr", err, url)
 	}
 
 	allXattr := string(buffer[:ret])
@@ -357,7 +406,7 @@ func (context Context) Getxattr(url string, name string) (string, GError) {
 
 	ret := C.gfal2_getxattr(context.cContext, cURL, cName, unsafe.Pointer(bufferPtr), C.size_t(len(buffer)), &err)
 	if ret < 0 {
-		return "", errorCtoGo(err)
+		return "", opErrorCtoGo("getxattr", err, url)
 	}
 
 	n := bytes.IndexByte(buffer, 0)
@@ -379,7 +428,7 @@ func (context Context) Setxattr(url string, name string, value string, flags int
 
 	ret := C.gfal2_setxattr(context.cContext, cURL, cName, unsafe.Pointer(cValue), C.size_t(len(value)), C.int(flags), &err)
 	if ret < 0 {
-		return errorCtoGo(err)
+		return opErrorCtoGo("setxattr", err, url)
 	}
 
 	return nil
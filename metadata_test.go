@@ -0,0 +1,39 @@
+package gfal2
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRemoveList(t *testing.T) {
+	context := getContext(t)
+	existing, _ := writeLocalFile(t, 16)
+	missing := "file://" + filepath.Join(t.TempDir(), "missing")
+
+	errs := context.RemoveList([]string{existing, missing})
+	if len(errs) != 2 {
+		t.Fatal("Was expecting one error per url, got ", len(errs))
+	}
+	if errs[0] != nil {
+		t.Error("Unexpected error removing an existing file: ", errs[0])
+	}
+	if _, err := os.Stat(strings.TrimPrefix(existing, "file://")); !os.IsNotExist(err) {
+		t.Error("The file should have been removed: ", err)
+	}
+
+	if !errors.Is(errs[1], fs.ErrNotExist) {
+		t.Error("Was expecting ErrNotExist, got ", errs[1])
+	}
+	var opErr *OpError
+	if !errors.As(errs[1], &opErr) || opErr.URL != missing {
+		t.Error("Was expecting an OpError for the missing url, got ", errs[1])
+	}
+
+	if errs := context.RemoveList(nil); len(errs) != 0 {
+		t.Error("Was expecting no error for an empty list, got ", errs)
+	}
+}
@@ -0,0 +1,292 @@
+/*
+ * Copyright (c) CERN 2016
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gfal2
+
+import (
+	"errors"
+	"io/fs"
+	neturl "net/url"
+	"path"
+	"strings"
+	"syscall"
+)
+
+// Support tells if a feature is supported, when it can be known.
+type Support int
+
+// Support values.
+const (
+	SupportUnknown Support = iota
+	Supported
+	Unsupported
+)
+
+// String returns a description of the value.
+func (support Support) String() string {
+	switch support {
+	case Supported:
+		return "supported"
+	case Unsupported:
+		return "unsupported"
+	}
+	return "unknown"
+}
+
+// TransferPlan describes what CopyFile would do, as found by Preflight.
+type TransferPlan struct {
+	Source      string
+	Destination string
+	// Url schemes of both ends, as "https" or "root".
+	SourceProtocol      string
+	DestinationProtocol string
+	// True if both ends can likely talk directly to each other, so the data does not go through the local node.
+	// It is a guess based on the protocols.
+	ThirdPartyCopy bool
+
+	SourceExists bool
+	SourceSize   int64
+
+	DestinationExists bool
+	Overwrite         bool
+	// Parent directory of the destination, whether it exists, and whether CopyFile would create it.
+	DestinationParent string
+	ParentExists      bool
+	CreateParent      bool
+
+	// Checksum configured on the handler, and whether each end supports it.
+	// The support of an end is only checked if the checksum mode requires it, and it is left
+	// to SupportUnknown if it could not be checked.
+	ChecksumMode               int
+	ChecksumType               string
+	SourceChecksum             string
+	SourceChecksumSupport      Support
+	DestinationChecksumSupport Support
+
+	// Reasons why CopyFile is expected to fail. Empty if the transfer looks feasible.
+	Problems []string
+	// Checks required by the handler configuration that could not be done, so CopyFile
+	// may still fail for these reasons.
+	Unverified []string
+}
+
+// OK returns true if no problem was found. Some checks may not have been done, see Unverified.
+func (plan *TransferPlan) OK() bool {
+	return len(plan.Problems) == 0
+}
+
+func (plan *TransferPlan) addProblem(problem string) {
+	plan.Problems = append(plan.Problems, problem)
+}
+
+func (plan *TransferPlan) addUnverified(check string) {
+	plan.Unverified = append(plan.Unverified, check)
+}
+
+// PreflightOptions configures Preflight. The zero value uses the defaults.
+type PreflightOptions struct {
+	// Compute the checksum of the source, to check that the source supports the checksum type
+	// configured on the handler, and that it matches the expected value, if any.
+	// For protocols that do not store checksums, this reads the whole file, so it is disabled by default.
+	ComputeSourceChecksum bool
+	// Compute the checksum of the destination, if it already exists, to check that it supports
+	// the checksum type configured on the handler. As for the source, this may read the whole file,
+	// which is about to be overwritten, so it is disabled by default.
+	ComputeDestinationChecksum bool
+}
+
+// Families of protocols that can do third party copies between each other.
+var tpcFamilies = map[string]string{
+	"http":   "http",
+	"https":  "http",
+	"dav":    "http",
+	"davs":   "http",
+	"s3":     "http",
+	"s3s":    "http",
+	"gcloud": "http",
+	"gsiftp": "gridftp",
+	"ftp":    "gridftp",
+	"root":   "xrootd",
+	"roots":  "xrootd",
+	"xroot":  "xrootd",
+}
+
+// Returns true if a third party copy between both protocols is likely possible.
+// SRM resolves to a transfer url, so it can pair with anything but local files.
+func thirdPartyPossible(source string, destination string) bool {
+	if source == "file" || destination == "file" || source == "" || destination == "" {
+		return false
+	}
+	if source == "srm" || destination == "srm" {
+		return true
+	}
+	family, ok := tpcFamilies[source]
+	return ok && family == tpcFamilies[destination]
+}
+
+// Returns the url of the parent directory.
+func parentURL(url string) string {
+	parsed, err := neturl.Parse(url)
+	if err != nil {
+		return ""
+	}
+	parsed.Path = path.Dir(strings.TrimSuffix(parsed.Path, "/"))
+	parsed.RawPath = ""
+	return parsed.String()
+}
+
+// Returns true if the checksum error means the checksum type is not supported.
+func isChecksumNotSupported(err error) bool {
+	code, ok := errorCode(err)
+	return ok && (code == syscall.ENOTSUP || code == syscall.ENOSYS || code == syscall.EINVAL)
+}
+
+// Preflight checks what CopyFile would do with the current parameters, without copying any data.
+// Problems that would make the copy fail are listed in the plan, and the checks that could not be done
+// in plan.Unverified.
+// The returned error is set if some check could not be completed, for reasons other than
+// a missing file; the plan is returned anyway, with what could be found.
+// The checksums of the source and of an existing destination are only computed if requested in opts,
+// since it may require reading the whole files. opts can be nil.
+func (params TransferHandler) Preflight(source string, destination string, opts *PreflightOptions) (*TransferPlan, GError) {
+	context := Context{cContext: params.cContext}
+	var options PreflightOptions
+	if opts != nil {
+		options = *opts
+	}
+	var firstErr GError
+	unexpected := func(err GError) bool {
+		if err == nil || errors.Is(err, fs.ErrNotExist) {
+			return false
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+		return true
+	}
+
+	plan := &TransferPlan{
+		Source:            source,
+		Destination:       destination,
+		Overwrite:         params.GetOverwrite(),
+		CreateParent:      params.GetCreateParentDir(),
+		DestinationParent: parentURL(destination),
+	}
+	if parsed, err := neturl.Parse(source); err == nil {
+		plan.SourceProtocol = parsed.Scheme
+	}
+	if parsed, err := neturl.Parse(destination); err == nil {
+		plan.DestinationProtocol = parsed.Scheme
+	}
+	plan.ThirdPartyCopy = thirdPartyPossible(plan.SourceProtocol, plan.DestinationProtocol)
+
+	// Source
+	sourceStat, err := context.Stat(source)
+	switch {
+	case err == nil:
+		plan.SourceExists = true
+		plan.SourceSize = sourceStat.Size()
+		if sourceStat.IsDir() {
+			plan.addProblem("source is a directory")
+		}
+	case unexpected(err):
+		plan.addProblem("can not stat the source: " + err.Error())
+	default:
+		plan.addProblem("source does not exist")
+	}
+
+	// Destination
+	destinationStat, err := context.Stat(destination)
+	switch {
+	case err == nil:
+		plan.DestinationExists = true
+		if destinationStat.IsDir() {
+			plan.addProblem("destination is a directory")
+		} else if !plan.Overwrite {
+			plan.addProblem("destination exists and overwrite is disabled")
+		}
+	case unexpected(err):
+		plan.addProblem("can not stat the destination: " + err.Error())
+	}
+
+	// Parent of the destination
+	if plan.DestinationExists {
+		plan.ParentExists = true
+	} else if plan.DestinationParent != "" {
+		parentStat, err := context.Stat(plan.DestinationParent)
+		switch {
+		case err == nil:
+			plan.ParentExists = parentStat.IsDir()
+			if !plan.ParentExists {
+				plan.addProblem("parent of the destination is not a directory")
+			}
+		case unexpected(err):
+			plan.addProblem("can not stat the parent of the destination: " + err.Error())
+		case !plan.CreateParent:
+			plan.addProblem("parent of the destination does not exist, and it would not be created")
+		}
+	}
+
+	// Checksums
+	mode, chkType, chkValue := params.GetChecksum()
+	if mode < 0 {
+		mode = ChecksumNone
+	}
+	plan.ChecksumMode, plan.ChecksumType = mode, chkType
+	if mode != ChecksumNone && chkType != "" {
+		checkSource := mode&ChecksumSource != 0 && plan.SourceExists && !sourceStat.IsDir()
+		if checkSource && !options.ComputeSourceChecksum {
+			plan.addUnverified("source checksum: not computed")
+		} else if checkSource {
+			value, err := context.Checksum(source, chkType, 0, 0)
+			switch {
+			case err == nil:
+				plan.SourceChecksumSupport = Supported
+				plan.SourceChecksum = value
+				if chkValue != "" && !checksumEqual(value, chkValue) {
+					plan.addProblem("source checksum " + value + " does not match the expected " + chkValue)
+				}
+			case isChecksumNotSupported(err):
+				plan.SourceChecksumSupport = Unsupported
+				plan.addProblem("source does not support " + chkType + " checksums")
+			default:
+				unexpected(err)
+				plan.addUnverified("source checksum: " + err.Error())
+			}
+		}
+		// The destination can only be checked if there is already a file there
+		checkDestination := mode&ChecksumTarget != 0 && plan.DestinationExists && !destinationStat.IsDir()
+		if mode&ChecksumTarget != 0 && !plan.DestinationExists {
+			plan.addUnverified("destination checksum: the destination does not exist yet")
+		} else if checkDestination && !options.ComputeDestinationChecksum {
+			plan.addUnverified("destination checksum: not computed")
+		} else if checkDestination {
+			_, err := context.Checksum(destination, chkType, 0, 0)
+			switch {
+			case err == nil:
+				plan.DestinationChecksumSupport = Supported
+			case isChecksumNotSupported(err):
+				plan.DestinationChecksumSupport = Unsupported
+				plan.addProblem("destination does not support " + chkType + " checksums")
+			default:
+				unexpected(err)
+				plan.addUnverified("destination checksum: " + err.Error())
+			}
+		}
+	}
+
+	return plan, firstErr
+}
@@ -0,0 +1,111 @@
+package gfal2
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestThirdPartyPossible(t *testing.T) {
+	cases := []struct {
+		source, destination string
+		expected            bool
+	}{
+		{"https", "davs", true},
+		{"root", "root", true},
+		{"gsiftp", "https", false},
+		{"file", "https", false},
+		{"srm", "gsiftp", true},
+	}
+	for _, c := range cases {
+		if thirdPartyPossible(c.source, c.destination) != c.expected {
+			t.Error("Unexpected result for ", c.source, " => ", c.destination)
+		}
+	}
+}
+
+func TestPreflight(t *testing.T) {
+	context := getContext(t)
+	handler, err := context.NewTransferHandler()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer handler.Close()
+	handler.SetCreateParentDir(false)
+
+	source, content := writeLocalFile(t, 1024)
+	destination := "file://" + filepath.Join(t.TempDir(), "missing", "copy")
+
+	plan, err := handler.Preflight(source, destination, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !plan.SourceExists || plan.SourceSize != int64(len(content)) || plan.DestinationExists || plan.ParentExists {
+		t.Errorf("Unexpected plan: %+v", plan)
+	}
+	if plan.OK() || plan.ThirdPartyCopy {
+		t.Errorf("Was expecting a missing parent: %+v", plan)
+	}
+
+	handler.SetCreateParentDir(true)
+	if plan, _ = handler.Preflight(source, destination, nil); !plan.OK() {
+		t.Error("Unexpected problems: ", plan.Problems)
+	}
+}
+
+func TestPreflightChecksum(t *testing.T) {
+	context := getContext(t)
+	handler, err := context.NewTransferHandler()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer handler.Close()
+
+	source, _ := writeLocalFile(t, 1024)
+	checksum, lerr := localChecksum(strings.TrimPrefix(source, "file://"), "adler32")
+	if lerr != nil {
+		t.Fatal(lerr)
+	}
+	destination := "file://" + filepath.Join(t.TempDir(), "copy")
+	handler.SetChecksum(ChecksumBoth, "adler32", checksum)
+
+	// Nothing is computed by default, and the destination does not exist
+	plan, err := handler.Preflight(source, destination, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !plan.OK() || len(plan.Unverified) != 2 || plan.SourceChecksumSupport != SupportUnknown || plan.DestinationChecksumSupport != SupportUnknown {
+		t.Errorf("Unexpected plan: %+v", plan)
+	}
+
+	plan, err = handler.Preflight(source, destination, &PreflightOptions{ComputeSourceChecksum: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !plan.OK() || len(plan.Unverified) != 1 || plan.SourceChecksumSupport != Supported || !checksumEqual(plan.SourceChecksum, checksum) {
+		t.Errorf("Unexpected plan: %+v", plan)
+	}
+
+	// An existing destination is not read by default either
+	existing, _ := writeLocalFile(t, 1024)
+	handler.SetOverwrite(true)
+	plan, err = handler.Preflight(source, existing, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !plan.OK() || len(plan.Unverified) != 2 || plan.DestinationChecksumSupport != SupportUnknown {
+		t.Errorf("Unexpected plan: %+v", plan)
+	}
+	plan, err = handler.Preflight(source, existing, &PreflightOptions{ComputeDestinationChecksum: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !plan.OK() || len(plan.Unverified) != 1 || plan.DestinationChecksumSupport != Supported {
+		t.Errorf("Unexpected plan: %+v", plan)
+	}
+
+	handler.SetChecksum(ChecksumBoth, "adler32", "00000001")
+	if plan, _ = handler.Preflight(source, destination, &PreflightOptions{ComputeSourceChecksum: true}); plan.OK() {
+		t.Error("Was expecting a checksum mismatch")
+	}
+}
@@ -0,0 +1,190 @@
+/*
+ * Copyright (c) CERN 2016
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gfal2
+
+import (
+	"sync"
+	"time"
+)
+
+// ProgressOptions configures a ProgressTracker.
+type ProgressOptions struct {
+	// Weight of the most recent sample in the smoothed throughput, between 0 and 1. Defaults to 0.3.
+	Smoothing float64
+	// Minimum time between two throughput samples. Defaults to 1 second.
+	SampleInterval time.Duration
+	// The transfer is considered stalled if no data moves for this long. Defaults to 1 minute.
+	StallTimeout time.Duration
+}
+
+// Progress is a snapshot of the state of a transfer.
+type Progress struct {
+	BytesTransferred uint64
+	// Size of the file, or -1 if unknown.
+	Total int64
+	// Percentage completed, from 0 to 100, or -1 if the size is unknown.
+	Percent float64
+	// Exponentially weighted moving average of the throughput, in bytes per second.
+	Throughput float64
+	// Estimated time remaining, or -1 if it can not be estimated.
+	ETA     time.Duration
+	Elapsed time.Duration
+	// True if no data has moved for longer than the stall timeout.
+	Stalled bool
+}
+
+// ProgressTracker computes the progress of a transfer.
+// It implements MonitorListener, so it can be added to a TransferHandler, and io.Writer,
+// so it can count the bytes of streamed File I/O, for instance with io.TeeReader or io.MultiWriter.
+// It is safe for concurrent use.
+type ProgressTracker struct {
+	options ProgressOptions
+	now     func() time.Time
+
+	mutex        sync.Mutex
+	total        int64
+	start        time.Time
+	bytes        uint64
+	sampleBytes  uint64
+	sampleTime   time.Time
+	lastProgress time.Time
+	throughput   float64
+	sampled      bool
+}
+
+// NewProgressTracker creates a tracker for a transfer of total bytes. Use a negative total if unknown.
+// opts can be nil.
+func NewProgressTracker(total int64, opts *ProgressOptions) *ProgressTracker {
+	return newProgressTracker(total, opts, time.Now)
+}
+
+func newProgressTracker(total int64, opts *ProgressOptions, now func() time.Time) *ProgressTracker {
+	var options ProgressOptions
+	if opts != nil {
+		options = *opts
+	}
+	if options.Smoothing <= 0 || options.Smoothing > 1 {
+		options.Smoothing = 0.3
+	}
+	if options.SampleInterval <= 0 {
+		options.SampleInterval = time.Second
+	}
+	if options.StallTimeout <= 0 {
+		options.StallTimeout = time.Minute
+	}
+	if total < 0 {
+		total = -1
+	}
+
+	start := now()
+	return &ProgressTracker{
+		options:      options,
+		now:          now,
+		total:        total,
+		start:        start,
+		sampleTime:   start,
+		lastProgress: start,
+	}
+}
+
+// NewProgressTracker creates a tracker for the transfer of url, taking the total size from Stat.
+func (context Context) NewProgressTracker(url string, opts *ProgressOptions) (*ProgressTracker, GError) {
+	stat, err := context.Stat(url)
+	if err != nil {
+		return nil, err
+	}
+	return NewProgressTracker(stat.Size(), opts), nil
+}
+
+// Update the count of transferred bytes. Must be called with the lock held.
+func (tracker *ProgressTracker) update(bytes uint64) {
+	now := tracker.now()
+	if bytes > tracker.bytes {
+		tracker.lastProgress = now
+	}
+	tracker.bytes = bytes
+
+	delta := now.Sub(tracker.sampleTime)
+	if delta < tracker.options.SampleInterval {
+		return
+	}
+	var instant float64
+	if bytes > tracker.sampleBytes {
+		instant = float64(bytes-tracker.sampleBytes) / delta.Seconds()
+	}
+	if tracker.sampled {
+		alpha := tracker.options.Smoothing
+		tracker.throughput = alpha*instant + (1-alpha)*tracker.throughput
+	} else {
+		tracker.throughput = instant
+		tracker.sampled = true
+	}
+	tracker.sampleBytes, tracker.sampleTime = bytes, now
+}
+
+// Add n bytes to the count of transferred bytes.
+func (tracker *ProgressTracker) Add(n int) {
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+	tracker.update(tracker.bytes + uint64(n))
+}
+
+// Write counts the bytes of p as transferred. It never fails.
+func (tracker *ProgressTracker) Write(p []byte) (int, error) {
+	tracker.Add(len(p))
+	return len(p), nil
+}
+
+// NotifyPerformanceMarker implements MonitorListener.
+func (tracker *ProgressTracker) NotifyPerformanceMarker(marker Marker) {
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+	tracker.update(marker.BytesTransferred)
+}
+
+// Progress returns the current state of the transfer.
+func (tracker *ProgressTracker) Progress() Progress {
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+
+	now := tracker.now()
+	progress := Progress{
+		BytesTransferred: tracker.bytes,
+		Total:            tracker.total,
+		Percent:          -1,
+		Throughput:       tracker.throughput,
+		ETA:              -1,
+		Elapsed:          now.Sub(tracker.start),
+	}
+
+	complete := false
+	if tracker.total >= 0 {
+		complete = tracker.bytes >= uint64(tracker.total)
+		if tracker.total == 0 || complete {
+			progress.Percent = 100
+			progress.ETA = 0
+		} else {
+			progress.Percent = 100 * float64(tracker.bytes) / float64(tracker.total)
+			if tracker.throughput > 0 {
+				remaining := float64(uint64(tracker.total) - tracker.bytes)
+				progress.ETA = time.Duration(remaining / tracker.throughput * float64(time.Second))
+			}
+		}
+	}
+	progress.Stalled = !complete && now.Sub(tracker.lastProgress) > tracker.options.StallTimeout
+	return progress
+}
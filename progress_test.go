@@ -0,0 +1,45 @@
+package gfal2
+
+import (
+	"testing"
+	"time"
+)
+
+func TestProgressTracker(t *testing.T) {
+	clock := time.Unix(1000, 0)
+	now := func() time.Time { return clock }
+
+	tracker := newProgressTracker(1000, &ProgressOptions{Smoothing: 0.5, StallTimeout: 10 * time.Second}, now)
+
+	clock = clock.Add(time.Second)
+	tracker.NotifyPerformanceMarker(Marker{BytesTransferred: 100})
+	progress := tracker.Progress()
+	if progress.Percent != 10 || progress.Throughput != 100 || progress.ETA != 9*time.Second {
+		t.Error("Unexpected progress: ", progress)
+	}
+
+	// Smoothed with the previous sample
+	clock = clock.Add(time.Second)
+	tracker.NotifyPerformanceMarker(Marker{BytesTransferred: 400})
+	progress = tracker.Progress()
+	if progress.Percent != 40 || progress.Throughput != 200 || progress.ETA != 3*time.Second {
+		t.Error("Unexpected progress: ", progress)
+	}
+
+	clock = clock.Add(11 * time.Second)
+	if progress = tracker.Progress(); !progress.Stalled {
+		t.Error("Was expecting a stalled transfer: ", progress)
+	}
+
+	tracker.Write(make([]byte, 600))
+	progress = tracker.Progress()
+	if progress.Percent != 100 || progress.ETA != 0 || progress.Stalled {
+		t.Error("Was expecting a complete transfer: ", progress)
+	}
+
+	unknown := newProgressTracker(-1, nil, now)
+	unknown.Add(10)
+	if progress = unknown.Progress(); progress.Percent != -1 || progress.ETA != -1 {
+		t.Error("Unexpected progress for an unknown size: ", progress)
+	}
+}
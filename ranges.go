@@ -0,0 +1,174 @@
+/*
+ * Copyright (c) CERN 2016
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gfal2
+
+import (
+	"io"
+	"sort"
+	"syscall"
+)
+
+// Range is a byte range of a file.
+type Range struct {
+	Offset int64
+	Length int64
+}
+
+// End returns the offset right after the last byte of the range.
+func (r Range) End() int64 {
+	return r.Offset + r.Length
+}
+
+// CoalescePolicy controls how ReadRanges merges nearby ranges into fewer, larger reads.
+type CoalescePolicy struct {
+	// Ranges separated by at most MaxGap bytes are read with a single request.
+	MaxGap int64
+	// A merged request never grows beyond MaxSize bytes, unless a single range is already larger.
+	// 0 means no limit.
+	MaxSize int64
+	// Maximum number of requests issued concurrently.
+	Concurrency int
+}
+
+// DefaultCoalescePolicy is the policy used by File.ReadRanges.
+var DefaultCoalescePolicy = CoalescePolicy{
+	MaxGap:      64 * 1024,
+	MaxSize:     8 * 1024 * 1024,
+	Concurrency: 8,
+}
+
+// coalescedRange is a merged request, and the index of the requested ranges it serves.
+type coalescedRange struct {
+	Range
+	members []int
+}
+
+// Coalesce sorts the ranges and merges those that overlap or are separated by at most MaxGap bytes.
+// It returns the list of requests to issue, ordered by offset.
+func (policy CoalescePolicy) Coalesce(ranges []Range) []Range {
+	groups := policy.coalesce(ranges)
+	merged := make([]Range, len(groups))
+	for i, group := range groups {
+		merged[i] = group.Range
+	}
+	return merged
+}
+
+func (policy CoalescePolicy) coalesce(ranges []Range) []coalescedRange {
+	order := make([]int, 0, len(ranges))
+	for i, r := range ranges {
+		if r.Length > 0 {
+			order = append(order, i)
+		}
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return ranges[order[a]].Offset < ranges[order[b]].Offset
+	})
+
+	var groups []coalescedRange
+	for _, index := range order {
+		r := ranges[index]
+		if n := len(groups); n > 0 {
+			last := &groups[n-1]
+			end := last.End()
+			if r.End() > end {
+				end = r.End()
+			}
+			fitsGap := r.Offset-last.End() <= policy.MaxGap
+			fitsSize := policy.MaxSize <= 0 || end-last.Offset <= policy.MaxSize || r.Offset < last.End()
+			if fitsGap && fitsSize {
+				last.Length = end - last.Offset
+				last.members = append(last.members, index)
+				continue
+			}
+		}
+		groups = append(groups, coalescedRange{Range: r, members: []int{index}})
+	}
+	return groups
+}
+
+// ReadRanges reads several, possibly scattered, byte ranges of the file using DefaultCoalescePolicy.
+// See ReadRangesPolicy.
+func (fd File) ReadRanges(ranges []Range) ([][]byte, error) {
+	return fd.ReadRangesPolicy(ranges, DefaultCoalescePolicy)
+}
+
+// ReadRangesPolicy reads several, possibly scattered, byte ranges of the file.
+// Nearby ranges are merged following policy, and the resulting requests are issued concurrently
+// with positional reads.
+// It returns one slice per requested range, in the same order. Slices of merged ranges share memory.
+// If a range goes beyond the end of the file, its slice is shorter, and the error is io.EOF.
+func (fd File) ReadRangesPolicy(ranges []Range, policy CoalescePolicy) ([][]byte, error) {
+	for _, r := range ranges {
+		if r.Offset < 0 || r.Length < 0 {
+			return nil, newOpError("read_ranges", &gErrorImpl{code: syscall.EINVAL, message: "invalid range"}, fd.url)
+		}
+	}
+
+	groups := policy.coalesce(ranges)
+	chunks := make([]chunk, len(groups))
+	index := make(map[int64]int, len(groups))
+	for i, group := range groups {
+		chunks[i] = chunk{offset: group.Offset, length: group.Length}
+		index[group.Offset] = i
+	}
+
+	concurrency := policy.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	results := make([][]byte, len(ranges))
+	for i := range results {
+		results[i] = []byte{}
+	}
+	short := make([]bool, len(groups))
+
+	err := processChunks(chunks, concurrency, func(worker int, c chunk) error {
+		i := index[c.offset]
+		buffer := make([]byte, c.length)
+		n, err := fd.ReadAt(buffer, c.offset)
+		if err == io.EOF {
+			short[i] = true
+		} else if err != nil {
+			return err
+		}
+
+		for _, member := range groups[i].members {
+			start := ranges[member].Offset - c.offset
+			end := start + ranges[member].Length
+			if start > int64(n) {
+				start = int64(n)
+			}
+			if end > int64(n) {
+				end = int64(n)
+			}
+			results[member] = buffer[start:end:end]
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, isShort := range short {
+		if isShort {
+			return results, io.EOF
+		}
+	}
+	return results, nil
+}
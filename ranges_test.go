@@ -0,0 +1,70 @@
+package gfal2
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestCoalesce(t *testing.T) {
+	policy := CoalescePolicy{MaxGap: 10, MaxSize: 50}
+	ranges := []Range{
+		{Offset: 200, Length: 10},
+		{Offset: 0, Length: 10},
+		{Offset: 15, Length: 10},
+		{Offset: 5, Length: 2},
+		{Offset: 60, Length: 10},
+		{Offset: 75, Length: 40},
+		{Offset: 300, Length: 0},
+	}
+	expected := []Range{
+		{Offset: 0, Length: 25},
+		{Offset: 60, Length: 10},
+		{Offset: 75, Length: 40},
+		{Offset: 200, Length: 10},
+	}
+
+	merged := policy.Coalesce(ranges)
+	if !reflect.DeepEqual(merged, expected) {
+		t.Error("Unexpected coalesced ranges: ", merged)
+	}
+}
+
+func TestReadRanges(t *testing.T) {
+	context := getContext(t)
+	url, content := writeLocalFile(t, 256*1024)
+
+	fd, err := context.Open(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fd.Close()
+
+	ranges := []Range{
+		{Offset: 100000, Length: 500},
+		{Offset: 10, Length: 100},
+		{Offset: 50, Length: 100},
+		{Offset: 200000, Length: 4096},
+		{Offset: 0, Length: 0},
+	}
+	policy := CoalescePolicy{MaxGap: 1024, MaxSize: 64 * 1024, Concurrency: 2}
+
+	results, rerr := fd.ReadRangesPolicy(ranges, policy)
+	if rerr != nil {
+		t.Fatal(rerr)
+	}
+	for i, r := range ranges {
+		if !bytes.Equal(results[i], content[r.Offset:r.End()]) {
+			t.Error("Unexpected content for range ", r)
+		}
+	}
+
+	results, rerr = fd.ReadRanges([]Range{{Offset: int64(len(content)) - 10, Length: 20}})
+	if rerr != io.EOF {
+		t.Error("Was expecting io.EOF, got ", rerr)
+	}
+	if len(results[0]) != 10 {
+		t.Error("Was expecting 10 bytes, got ", len(results[0]))
+	}
+}
@@ -0,0 +1,149 @@
+/*
+ * Copyright (c) CERN 2016
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gfal2
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// RemoveAllOptions configures RemoveAll. The zero value uses the defaults.
+type RemoveAllOptions struct {
+	// Number of concurrent deletions, and of directories read concurrently. Defaults to 8.
+	Concurrency int
+	// Do not delete anything, just report what would be deleted.
+	DryRun bool
+}
+
+// RemoveFailure describes a path that could not be listed or removed.
+type RemoveFailure struct {
+	URL string
+	Err error
+}
+
+// RemoveAllReport summarises what RemoveAll did.
+type RemoveAllReport struct {
+	// Paths removed, or that would be removed in dry-run mode. Files first, then directories bottom-up.
+	Removed []string
+	// Paths that could not be listed or removed.
+	Failed []RemoveFailure
+}
+
+// Run fn over items, using up to n goroutines.
+func forEachConcurrent(items []string, n int, fn func(item string)) {
+	queue := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for item := range queue {
+				fn(item)
+			}
+		}()
+	}
+	for _, item := range items {
+		queue <- item
+	}
+	close(queue)
+	wg.Wait()
+}
+
+// RemoveAll deletes url and, if it is a directory, everything it contains.
+// The tree is walked first; then files are deleted concurrently, and finally directories are
+// removed bottom-up. Failures do not stop the process; they are collected in the report,
+// and the first one is also returned as the error.
+// With opts.DryRun, nothing is deleted, and the report lists the paths that would be removed.
+func (context Context) RemoveAll(url string, opts *RemoveAllOptions) (*RemoveAllReport, error) {
+	var options RemoveAllOptions
+	if opts != nil {
+		options = *opts
+	}
+	if options.Concurrency <= 0 {
+		options.Concurrency = 8
+	}
+
+	report := &RemoveAllReport{}
+	var mutex sync.Mutex
+	fail := func(url string, err error) {
+		mutex.Lock()
+		report.Failed = append(report.Failed, RemoveFailure{URL: url, Err: err})
+		mutex.Unlock()
+	}
+	removed := func(url string) {
+		mutex.Lock()
+		report.Removed = append(report.Removed, url)
+		mutex.Unlock()
+	}
+
+	var files []string
+	dirsByDepth := make(map[int][]string)
+	root := strings.TrimRight(url, "/")
+
+	walkErr := context.Walk(url, func(entry string, info Stat) error {
+		if info.IsDir() {
+			depth := strings.Count(strings.TrimPrefix(entry, root), "/")
+			dirsByDepth[depth] = append(dirsByDepth[depth], entry)
+		} else {
+			files = append(files, entry)
+		}
+		return nil
+	}, &WalkOptions{
+		Concurrency: options.Concurrency,
+		OnDirError: func(dir string, err error) error {
+			fail(dir, err)
+			return nil
+		},
+	})
+	if walkErr != nil {
+		return report, walkErr
+	}
+
+	forEachConcurrent(files, options.Concurrency, func(file string) {
+		if !options.DryRun {
+			if err := context.unlink(file); err != nil {
+				fail(file, err)
+				return
+			}
+		}
+		removed(file)
+	})
+
+	depths := make([]int, 0, len(dirsByDepth))
+	for depth := range dirsByDepth {
+		depths = append(depths, depth)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(depths)))
+
+	for _, depth := range depths {
+		forEachConcurrent(dirsByDepth[depth], options.Concurrency, func(dir string) {
+			if !options.DryRun {
+				if err := context.rmdir(dir); err != nil {
+					fail(dir, err)
+					return
+				}
+			}
+			removed(dir)
+		})
+	}
+
+	if len(report.Failed) > 0 {
+		return report, report.Failed[0].Err
+	}
+	return report, nil
+}
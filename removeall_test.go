@@ -0,0 +1,99 @@
+package gfal2
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var removeTree = []string{"a/one", "a/sub/two", "a/sub/deep/three", "b/four", "empty/", "five"}
+
+func TestRemoveAll(t *testing.T) {
+	context := getContext(t)
+	root := makeTree(t, removeTree...)
+	url := "file://" + root
+
+	report, err := context.RemoveAll(url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(root); !os.IsNotExist(err) {
+		t.Error("The tree should have been removed: ", err)
+	}
+	// root, 5 directories and 5 files
+	if len(report.Removed) != 11 || len(report.Failed) != 0 {
+		t.Fatal("Unexpected report: ", report)
+	}
+
+	// Files first, and every directory after everything it contained
+	dirs := map[string]bool{url: true}
+	for _, dir := range []string{"a", "a/sub", "a/sub/deep", "b", "empty"} {
+		dirs[url+"/"+dir] = true
+	}
+	seenDir := false
+	for i, removed := range report.Removed {
+		if dirs[removed] {
+			seenDir = true
+		} else if seenDir {
+			t.Error("File removed after a directory: ", removed)
+		}
+		for _, later := range report.Removed[i+1:] {
+			if strings.HasPrefix(later, removed+"/") {
+				t.Errorf("%s removed after its parent %s", later, removed)
+			}
+		}
+	}
+	if report.Removed[len(report.Removed)-1] != url {
+		t.Error("The root should be removed last: ", report.Removed)
+	}
+}
+
+func TestRemoveAllDryRun(t *testing.T) {
+	context := getContext(t)
+	root := makeTree(t, removeTree...)
+
+	report, err := context.RemoveAll("file://"+root, &RemoveAllOptions{DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Removed) != 11 {
+		t.Error("Unexpected report: ", report)
+	}
+	for _, name := range removeTree {
+		if _, err := os.Stat(filepath.Join(root, name)); err != nil {
+			t.Error("Nothing should have been removed: ", err)
+		}
+	}
+}
+
+func TestRemoveAllFailure(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("Permissions are not enforced for root")
+	}
+	context := getContext(t)
+	root := makeTree(t, append(removeTree, "locked/file")...)
+	locked := filepath.Join(root, "locked")
+	if err := os.Chmod(locked, 0555); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chmod(locked, 0755) })
+
+	report, err := context.RemoveAll("file://"+root, nil)
+	if !errors.Is(err, os.ErrPermission) {
+		t.Error("Was expecting a permission error, got ", err)
+	}
+
+	failed := make(map[string]bool)
+	for _, failure := range report.Failed {
+		failed[strings.TrimPrefix(failure.URL, "file://"+root)] = true
+	}
+	// The file can not be unlinked, so its parents can not be removed either
+	if len(failed) != 3 || !failed["/locked/file"] || !failed["/locked"] || !failed[""] {
+		t.Error("Unexpected failures: ", report.Failed)
+	}
+	if _, err := os.Stat(filepath.Join(root, "a")); !os.IsNotExist(err) {
+		t.Error("The other entries should have been removed: ", err)
+	}
+}
@@ -0,0 +1,176 @@
+/*
+ * Copyright (c) CERN 2016
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gfal2
+
+import (
+	"sync"
+	"time"
+)
+
+// TransferReport summarises a transfer done by CopyFileWithReport. It can be serialized to JSON.
+type TransferReport struct {
+	Source           string `json:"source"`
+	Destination      string `json:"destination"`
+	SourceSize       int64  `json:"source_size"`
+	DestinationSize  int64  `json:"destination_size"`
+	BytesTransferred uint64 `json:"bytes_transferred"`
+
+	// Checksum mode, type and value configured on the handler.
+	ChecksumMode  int    `json:"checksum_mode"`
+	ChecksumType  string `json:"checksum_type,omitempty"`
+	ChecksumValue string `json:"checksum_value,omitempty"`
+	// Checksums of the ends validated by the checksum mode, queried after the transfer.
+	SourceChecksum      string `json:"source_checksum,omitempty"`
+	DestinationChecksum string `json:"destination_checksum,omitempty"`
+
+	StartTime time.Time     `json:"start_time"`
+	EndTime   time.Time     `json:"end_time"`
+	Duration  time.Duration `json:"duration"`
+	// Throughputs, in bytes per second, as reported by the performance markers.
+	AvgThroughput  uint64 `json:"avg_throughput"`
+	PeakThroughput uint64 `json:"peak_throughput"`
+	Streams        int    `json:"streams"`
+
+	Events []Event `json:"events"`
+	// Time spent in each phase of the transfer, from the events.
+	Phases []PhaseTiming `json:"phases"`
+
+	// Set if the transfer failed.
+	Error     string `json:"error,omitempty"`
+	ErrorCode int    `json:"error_code,omitempty"`
+	// Stage and side (one of ErrorSideSource, ErrorSideDestination or ErrorSideTransfer) that failed, if known.
+	FailedStage string `json:"failed_stage,omitempty"`
+	FailedSide  string `json:"failed_side,omitempty"`
+}
+
+// reportCollector gathers the markers and events of a transfer.
+type reportCollector struct {
+	mutex  sync.Mutex
+	last   Marker
+	peak   uint64
+	events []Event
+}
+
+// NotifyPerformanceMarker implements MonitorListener.
+func (collector *reportCollector) NotifyPerformanceMarker(marker Marker) {
+	collector.mutex.Lock()
+	defer collector.mutex.Unlock()
+	collector.last = marker
+	if marker.InstantThroughput > collector.peak {
+		collector.peak = marker.InstantThroughput
+	}
+}
+
+// NotifyEvent implements EventListener.
+func (collector *reportCollector) NotifyEvent(event Event) {
+	collector.mutex.Lock()
+	defer collector.mutex.Unlock()
+	collector.events = append(collector.events, event)
+}
+
+// Name of the side of an event, as the ErrorSide constants.
+func eventSideName(side int) string {
+	switch side {
+	case EventSource:
+		return ErrorSideSource
+	case EventDestination:
+		return ErrorSideDestination
+	}
+	return ErrorSideTransfer
+}
+
+// CopyFileWithReport is like CopyFile, but it also returns a report of the transfer.
+// The report is returned even if the copy fails, in which case it records the error.
+func (params TransferHandler) CopyFileWithReport(source string, destination string) (*TransferReport, GError) {
+	context := Context{cContext: params.cContext}
+	report := &TransferReport{
+		Source:      source,
+		Destination: destination,
+		Streams:     params.GetNoStreams(),
+	}
+	report.ChecksumMode, report.ChecksumType, report.ChecksumValue = params.GetChecksum()
+	if report.ChecksumMode < 0 {
+		report.ChecksumMode = ChecksumNone
+	}
+
+	if stat, err := context.Stat(source); err == nil {
+		report.SourceSize = stat.Size()
+	}
+
+	collector := &reportCollector{}
+	if err := params.AddMonitorCallback(collector); err != nil {
+		return nil, err
+	}
+	defer params.RemoveMonitorCallback(collector)
+	if err := params.AddEventCallback(collector); err != nil {
+		return nil, err
+	}
+	defer params.RemoveEventCallback(collector)
+
+	report.StartTime = time.Now()
+	copyErr := params.CopyFile(source, destination)
+	report.EndTime = time.Now()
+	report.Duration = report.EndTime.Sub(report.StartTime)
+
+	collector.mutex.Lock()
+	report.BytesTransferred = collector.last.BytesTransferred
+	report.AvgThroughput = collector.last.AvgThroughput
+	report.PeakThroughput = collector.peak
+	report.Events = collector.events
+	collector.mutex.Unlock()
+	report.Phases = ParsePhases(report.Events)
+
+	if copyErr != nil {
+		details := ParseError(copyErr)
+		report.Error = copyErr.Error()
+		report.ErrorCode = int(copyErr.Code())
+		report.FailedStage = details.Stage
+		report.FailedSide = details.Side
+		// Otherwise, blame the last thing gfal2 was doing
+		if n := len(report.Events); n > 0 {
+			last := report.Events[n-1]
+			if report.FailedStage == "" {
+				report.FailedStage = Stage(last.Stage).Name()
+			}
+			if report.FailedSide == "" {
+				report.FailedSide = eventSideName(last.Side)
+			}
+		}
+		return report, copyErr
+	}
+
+	if stat, err := context.Stat(destination); err == nil {
+		report.DestinationSize = stat.Size()
+	}
+	if report.BytesTransferred == 0 {
+		report.BytesTransferred = uint64(report.DestinationSize)
+	}
+	if report.AvgThroughput == 0 && report.Duration > 0 {
+		report.AvgThroughput = uint64(float64(report.BytesTransferred) / report.Duration.Seconds())
+	}
+	// Only the ends gfal2 already validated, since it may mean reading the whole file again
+	if report.ChecksumType != "" {
+		if report.ChecksumMode&ChecksumSource != 0 {
+			report.SourceChecksum, _ = context.Checksum(source, report.ChecksumType, 0, 0)
+		}
+		if report.ChecksumMode&ChecksumTarget != 0 {
+			report.DestinationChecksum, _ = context.Checksum(destination, report.ChecksumType, 0, 0)
+		}
+	}
+
+	return report, nil
+}
@@ -0,0 +1,66 @@
+package gfal2
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+)
+
+func TestCopyFileWithReport(t *testing.T) {
+	context := getContext(t)
+	handler, err := context.NewTransferHandler()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer handler.Close()
+
+	source, content := writeLocalFile(t, 64*1024)
+	destination := "file://" + filepath.Join(t.TempDir(), "copy")
+
+	report, err := handler.CopyFileWithReport(source, destination)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.SourceSize != int64(len(content)) || report.DestinationSize != int64(len(content)) {
+		t.Error("Unexpected sizes: ", report.SourceSize, report.DestinationSize)
+	}
+	if report.EndTime.Before(report.StartTime) || report.Error != "" {
+		t.Error("Unexpected report: ", report)
+	}
+
+	serialized, jerr := json.Marshal(report)
+	if jerr != nil {
+		t.Fatal(jerr)
+	}
+	var decoded TransferReport
+	if jerr := json.Unmarshal(serialized, &decoded); jerr != nil || decoded.Source != source {
+		t.Error("Could not deserialize the report: ", jerr)
+	}
+
+	report, err = handler.CopyFileWithReport(source+".missing", destination+".missing")
+	if err == nil || report == nil || report.Error == "" || report.ErrorCode == 0 {
+		t.Error("Was expecting a failure to be reported, got ", report, err)
+	}
+}
+
+func TestCopyFileWithReportChecksum(t *testing.T) {
+	context := getContext(t)
+	handler, err := context.NewTransferHandler()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer handler.Close()
+	handler.SetChecksum(ChecksumTarget, "adler32", "")
+
+	source, _ := writeLocalFile(t, 64*1024)
+	destination := "file://" + filepath.Join(t.TempDir(), "copy")
+
+	report, err := handler.CopyFileWithReport(source, destination)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Only the destination is validated, so only its checksum is queried
+	if report.SourceChecksum != "" || report.DestinationChecksum == "" {
+		t.Error("Unexpected checksums: ", report.SourceChecksum, report.DestinationChecksum)
+	}
+}
@@ -0,0 +1,185 @@
+/*
+ * Copyright (c) CERN 2016
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gfal2
+
+import (
+	gocontext "context"
+	"errors"
+	"math/rand/v2"
+	"syscall"
+	"time"
+)
+
+// RetryClassifier decides if an error with the given code and domain is worth retrying.
+type RetryClassifier func(code syscall.Errno, domain string) bool
+
+// DefaultRetryClassifier retries the transient error codes, regardless of the domain. See IsTransient.
+func DefaultRetryClassifier(code syscall.Errno, domain string) bool {
+	return transientCodes[code]
+}
+
+// RetryAttempt describes an attempt, and it is passed to RetryPolicy.OnAttempt.
+type RetryAttempt struct {
+	// Attempt number, starting at 1.
+	Number int
+	// Error returned by the attempt; nil if it succeeded.
+	Err error
+	// Duration of the attempt.
+	Duration time.Duration
+	// True if there will be another attempt.
+	WillRetry bool
+	// Time to wait before the next attempt, if any.
+	Delay time.Duration
+}
+
+// RetryPolicy describes how, and how many times, an operation is retried.
+type RetryPolicy struct {
+	// Maximum number of attempts, including the first one. Values lower than 1 mean 1.
+	MaxAttempts int
+	// Delay before the first retry.
+	InitialBackoff time.Duration
+	// Upper bound of the delay between attempts. 0 means no limit.
+	MaxBackoff time.Duration
+	// Factor applied to the delay after each retry. Values lower than 1 mean 2.
+	Multiplier float64
+	// Randomization applied to each delay, as a fraction of it. For instance, 0.2 means +/- 20%.
+	Jitter float64
+	// Timeout of each attempt. 0 means no timeout other than the one of the parent context.
+	AttemptTimeout time.Duration
+	// Decides if a failed attempt is retried. If nil, DefaultRetryClassifier is used.
+	// Errors that are not a GError, nor a syscall.Errno, are never retried.
+	Classifier RetryClassifier
+	// If set, it is called after every attempt.
+	OnAttempt func(attempt RetryAttempt)
+}
+
+// DefaultRetryPolicy does three attempts, with an exponential backoff starting at one second.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    3,
+	InitialBackoff: time.Second,
+	MaxBackoff:     30 * time.Second,
+	Multiplier:     2,
+	Jitter:         0.2,
+}
+
+// Returns true if err can be retried, according to the policy.
+func (policy RetryPolicy) retryable(err error) bool {
+	code, ok := errorCode(err)
+	if !ok {
+		return false
+	}
+	domain := ""
+	var gerr GError
+	if errors.As(err, &gerr) {
+		domain = gerr.Domain()
+	}
+
+	classifier := policy.Classifier
+	if classifier == nil {
+		classifier = DefaultRetryClassifier
+	}
+	return classifier(code, domain)
+}
+
+// Returns the delay to wait before the given retry, starting at 1.
+func (policy RetryPolicy) backoff(retry int) time.Duration {
+	multiplier := policy.Multiplier
+	if multiplier < 1 {
+		multiplier = 2
+	}
+
+	delay := float64(policy.InitialBackoff)
+	for i := 1; i < retry; i++ {
+		delay *= multiplier
+		if policy.MaxBackoff > 0 && delay > float64(policy.MaxBackoff) {
+			break
+		}
+	}
+	if policy.MaxBackoff > 0 && delay > float64(policy.MaxBackoff) {
+		delay = float64(policy.MaxBackoff)
+	}
+	if policy.Jitter > 0 {
+		delay += delay * policy.Jitter * (2*rand.Float64() - 1)
+	}
+	if delay < 0 {
+		delay = 0
+	}
+	return time.Duration(delay)
+}
+
+// Do runs op until it succeeds, it fails with an error that is not retryable, the attempts are exhausted,
+// or ctx is done. op receives a context that expires after AttemptTimeout, and it should pass it down
+// to the context aware methods, such as StatContext or CopyFileContext.
+// Do returns the error of the last attempt. If ctx is done while waiting to retry, the error
+// satisfies errors.Is(err, context.Canceled) or errors.Is(err, context.DeadlineExceeded).
+func (policy RetryPolicy) Do(ctx gocontext.Context, op func(ctx gocontext.Context) error) error {
+	maxAttempts := policy.MaxAttempts
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+
+	for attempt := 1; ; attempt++ {
+		attemptCtx, cancel := ctx, gocontext.CancelFunc(func() {})
+		if policy.AttemptTimeout > 0 {
+			attemptCtx, cancel = gocontext.WithTimeout(ctx, policy.AttemptTimeout)
+		}
+		start := time.Now()
+		err := op(attemptCtx)
+		cancel()
+
+		info := RetryAttempt{Number: attempt, Err: err, Duration: time.Since(start)}
+		if err != nil && attempt < maxAttempts && ctx.Err() == nil && policy.retryable(err) {
+			info.WillRetry = true
+			info.Delay = policy.backoff(attempt)
+		}
+		if policy.OnAttempt != nil {
+			policy.OnAttempt(info)
+		}
+		if !info.WillRetry {
+			return err
+		}
+
+		timer := time.NewTimer(info.Delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			var gerr GError
+			errors.As(err, &gerr)
+			return newContextError(ctx.Err(), gerr)
+		}
+	}
+}
+
+// RetryValue is like RetryPolicy.Do, for operations that return a value as well.
+func RetryValue[T any](ctx gocontext.Context, policy RetryPolicy, op func(ctx gocontext.Context) (T, error)) (T, error) {
+	var value T
+	err := policy.Do(ctx, func(ctx gocontext.Context) error {
+		var err error
+		value, err = op(ctx)
+		return err
+	})
+	return value, err
+}
+
+// CopyFileRetry runs CopyFileContext under policy.
+// Consider enabling SetOverwrite, since a failed attempt may leave a partial destination behind.
+func (params TransferHandler) CopyFileRetry(ctx gocontext.Context, policy RetryPolicy, source string, destination string) error {
+	return policy.Do(ctx, func(ctx gocontext.Context) error {
+		return params.CopyFileContext(ctx, source, destination)
+	})
+}
@@ -0,0 +1,76 @@
+package gfal2
+
+import (
+	gocontext "context"
+	"errors"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicy(t *testing.T) {
+	var attempts []RetryAttempt
+	policy := RetryPolicy{
+		MaxAttempts:    4,
+		InitialBackoff: time.Millisecond,
+		OnAttempt: func(attempt RetryAttempt) {
+			attempts = append(attempts, attempt)
+		},
+	}
+
+	calls := 0
+	err := policy.Do(gocontext.Background(), func(ctx gocontext.Context) error {
+		calls++
+		if calls < 3 {
+			return gErrorImpl{code: syscall.EAGAIN, message: "try again"}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal("Was expecting a success, got ", err)
+	}
+	if calls != 3 || len(attempts) != 3 {
+		t.Fatal("Was expecting 3 attempts, got ", calls, len(attempts))
+	}
+	if !attempts[0].WillRetry || !attempts[1].WillRetry || attempts[2].WillRetry || attempts[2].Err != nil {
+		t.Error("Unexpected attempts: ", attempts)
+	}
+
+	// Permanent errors are not retried
+	attempts = nil
+	err = policy.Do(gocontext.Background(), func(ctx gocontext.Context) error {
+		return gErrorImpl{code: syscall.ENOENT, message: "not found"}
+	})
+	if !errors.Is(err, syscall.ENOENT) || len(attempts) != 1 {
+		t.Error("Was expecting a single attempt, got ", len(attempts), err)
+	}
+
+	// The classifier can take the domain into account
+	attempts = nil
+	policy.Classifier = func(code syscall.Errno, domain string) bool {
+		return domain == "http_plugin" && code == syscall.EIO
+	}
+	err = policy.Do(gocontext.Background(), func(ctx gocontext.Context) error {
+		return gErrorImpl{domain: "http_plugin", code: syscall.EIO, message: "server error"}
+	})
+	if err == nil || len(attempts) != 4 {
+		t.Error("Was expecting 4 attempts, got ", len(attempts), err)
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	policy := RetryPolicy{InitialBackoff: time.Second, MaxBackoff: 5 * time.Second, Multiplier: 2}
+	expected := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for i, delay := range expected {
+		if got := policy.backoff(i + 1); got != delay {
+			t.Errorf("Retry %d: expected %v, got %v", i+1, delay, got)
+		}
+	}
+
+	policy.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		if got := policy.backoff(1); got < 500*time.Millisecond || got > 1500*time.Millisecond {
+			t.Fatal("Jitter out of bounds: ", got)
+		}
+	}
+}
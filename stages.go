@@ -0,0 +1,191 @@
+/*
+ * Copyright (c) CERN 2016
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gfal2
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// Stage is the stage of a transfer event, as found in Event.Stage.
+type Stage string
+
+// Stages triggered by gfal2 and its plugins.
+const (
+	StagePrepareEnter  Stage = "PREPARE:ENTER"
+	StagePrepareExit   Stage = "PREPARE:EXIT"
+	StageTransferEnter Stage = "TRANSFER:ENTER"
+	StageTransferExit  Stage = "TRANSFER:EXIT"
+	StageTransferType  Stage = "TRANSFER:TYPE"
+	StageChecksumEnter Stage = "CHECKSUM:ENTER"
+	StageChecksumExit  Stage = "CHECKSUM:EXIT"
+	StageClosingEnter  Stage = "CLOSING:ENTER"
+	StageClosingExit   Stage = "CLOSING:EXIT"
+	StageCancelEnter   Stage = "CANCEL:ENTER"
+	StageCancelExit    Stage = "CANCEL:EXIT"
+	StageListEnter     Stage = "LIST:ENTER"
+	StageListItem      Stage = "LIST:ITEM"
+	StageListExit      Stage = "LIST:EXIT"
+	StageOverwrite     Stage = "OVERWRITE"
+)
+
+// Name returns the stage without the boundary. For instance, "TRANSFER" for "TRANSFER:ENTER".
+func (stage Stage) Name() string {
+	name, _, _ := strings.Cut(string(stage), ":")
+	return name
+}
+
+// IsEnter returns true if the stage marks the beginning of a phase.
+func (stage Stage) IsEnter() bool {
+	return strings.HasSuffix(string(stage), ":ENTER")
+}
+
+// IsExit returns true if the stage marks the end of a phase.
+func (stage Stage) IsExit() bool {
+	return strings.HasSuffix(string(stage), ":EXIT")
+}
+
+// Phase is a part of a transfer, delimited by a pair of ENTER and EXIT events.
+type Phase string
+
+// Transfer phases.
+const (
+	PhasePreparation         Phase = "preparation"
+	PhaseSourceChecksum      Phase = "source_checksum"
+	PhaseTransfer            Phase = "transfer"
+	PhaseDestinationChecksum Phase = "destination_checksum"
+	PhaseCleanup             Phase = "cleanup"
+	PhaseOther               Phase = "other"
+)
+
+// PhaseTiming is the time spent in a phase of a transfer.
+type PhaseTiming struct {
+	Phase Phase `json:"phase"`
+	// Name of the stage, as "TRANSFER" or "CHECKSUM".
+	Stage string `json:"stage"`
+	// Side of the events, as in Event.Side.
+	Side     int           `json:"side"`
+	Start    time.Time     `json:"start"`
+	End      time.Time     `json:"end"`
+	Duration time.Duration `json:"duration"`
+	// False if the EXIT event is missing, as when the transfer fails in the middle of the phase.
+	// End is then the time of the last event.
+	Complete bool `json:"complete"`
+}
+
+// Converts an event timestamp, in milliseconds since the epoch.
+func eventTime(event Event) time.Time {
+	return time.UnixMilli(int64(event.Timestamp))
+}
+
+// Returns the phase of a stage. Checksums are attributed by side, or, if unknown, by whether
+// the transfer already happened.
+func phaseOf(name string, side int, transferSeen bool) Phase {
+	switch name {
+	case "PREPARE":
+		return PhasePreparation
+	case "TRANSFER":
+		return PhaseTransfer
+	case "CHECKSUM":
+		switch {
+		case side == EventSource:
+			return PhaseSourceChecksum
+		case side == EventDestination:
+			return PhaseDestinationChecksum
+		case transferSeen:
+			return PhaseDestinationChecksum
+		default:
+			return PhaseSourceChecksum
+		}
+	case "CLOSING", "CANCEL":
+		return PhaseCleanup
+	}
+	return PhaseOther
+}
+
+// ParsePhases pairs the ENTER and EXIT events of a transfer into phases with their duration,
+// ordered by start time. Events that do not delimit a phase are ignored.
+func ParsePhases(events []Event) []PhaseTiming {
+	type openPhase struct {
+		index int
+		name  string
+		side  int
+	}
+
+	var phases []PhaseTiming
+	var open []openPhase
+	var last time.Time
+	transferSeen := false
+
+	for _, event := range events {
+		stage := Stage(event.Stage)
+		when := eventTime(event)
+		if when.After(last) {
+			last = when
+		}
+
+		switch {
+		case stage.IsEnter():
+			name := stage.Name()
+			open = append(open, openPhase{index: len(phases), name: name, side: event.Side})
+			phases = append(phases, PhaseTiming{
+				Phase: phaseOf(name, event.Side, transferSeen),
+				Stage: name,
+				Side:  event.Side,
+				Start: when,
+			})
+		case stage.IsExit():
+			name := stage.Name()
+			// Most recent phase with the same name, preferably on the same side
+			match := -1
+			for i := len(open) - 1; i >= 0; i-- {
+				if open[i].name == name {
+					if open[i].side == event.Side {
+						match = i
+						break
+					}
+					if match < 0 {
+						match = i
+					}
+				}
+			}
+			if match < 0 {
+				continue
+			}
+			phase := &phases[open[match].index]
+			phase.End = when
+			phase.Duration = when.Sub(phase.Start)
+			phase.Complete = true
+			open = append(open[:match], open[match+1:]...)
+			if name == "TRANSFER" {
+				transferSeen = true
+			}
+		}
+	}
+
+	for _, unfinished := range open {
+		phase := &phases[unfinished.index]
+		phase.End = last
+		phase.Duration = last.Sub(phase.Start)
+	}
+
+	sort.SliceStable(phases, func(i, j int) bool {
+		return phases[i].Start.Before(phases[j].Start)
+	})
+	return phases
+}
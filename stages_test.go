@@ -0,0 +1,46 @@
+package gfal2
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParsePhases(t *testing.T) {
+	events := []Event{
+		{Stage: "PREPARE:ENTER", Side: EventNone, Timestamp: 1000},
+		{Stage: "PREPARE:EXIT", Side: EventNone, Timestamp: 1100},
+		{Stage: "CHECKSUM:ENTER", Side: EventSource, Timestamp: 1100},
+		{Stage: "CHECKSUM:EXIT", Side: EventSource, Timestamp: 1300},
+		{Stage: "TRANSFER:TYPE", Side: EventNone, Timestamp: 1300},
+		{Stage: "TRANSFER:ENTER", Side: EventNone, Timestamp: 1300},
+		{Stage: "TRANSFER:EXIT", Side: EventNone, Timestamp: 6300},
+		{Stage: "CHECKSUM:ENTER", Side: EventNone, Timestamp: 6300},
+		{Stage: "CHECKSUM:EXIT", Side: EventNone, Timestamp: 6500},
+		{Stage: "CLOSING:ENTER", Side: EventNone, Timestamp: 6500},
+	}
+
+	phases := ParsePhases(events)
+	expected := []struct {
+		phase    Phase
+		duration time.Duration
+		complete bool
+	}{
+		{PhasePreparation, 100 * time.Millisecond, true},
+		{PhaseSourceChecksum, 200 * time.Millisecond, true},
+		{PhaseTransfer, 5 * time.Second, true},
+		{PhaseDestinationChecksum, 200 * time.Millisecond, true},
+		{PhaseCleanup, 0, false},
+	}
+	if len(phases) != len(expected) {
+		t.Fatal("Unexpected phases: ", phases)
+	}
+	for i, e := range expected {
+		if phases[i].Phase != e.phase || phases[i].Duration != e.duration || phases[i].Complete != e.complete {
+			t.Errorf("Phase %d: expected %v, got %+v", i, e, phases[i])
+		}
+	}
+
+	if StageTransferEnter.Name() != "TRANSFER" || !StageTransferEnter.IsEnter() || StageTransferEnter.IsExit() {
+		t.Error("Unexpected parsing of ", StageTransferEnter)
+	}
+}
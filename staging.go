@@ -44,7 +44,7 @@ func (context Context) BringOnline(url string, pintime int, timeout int, async b
 
 	ret := C.gfal2_bring_online(context.cContext, cURL, C.time_t(pintime), C.time_t(timeout), bufferPtr, C.size_t(len(buffer)), cAsync, &err)
 	if ret < 0 {
-		return "", errorCtoGo(err)
+		return "", opErrorCtoGo("bring_online", err, url)
 	}
 
 	n := bytes.IndexByte(buffer, 0)
@@ -63,7 +63,7 @@ func (context Context) BringOnlinePoll(url string, token string) GError {
 
 	ret := C.gfal2_bring_online_poll(context.cContext, cURL, cToken, &err)
 	if ret < 0 {
-		return errorCtoGo(err)
+		return opErrorCtoGo("bring_online_poll", err, url)
 	}
 
 	return nil
@@ -81,7 +81,7 @@ func (context Context) ReleaseFile(url string, token string) GError {
 
 	ret := C.gfal2_release_file(context.cContext, cURL, cToken, &err)
 	if ret < 0 {
-		return errorCtoGo(err)
+		return opErrorCtoGo("release_file", err, url)
 	}
 
 	return nil
@@ -120,11 +120,11 @@ func (context Context) BringOnlineList(urls []string, pintime int, timeout int,
 	for i := 0; i < nItems; i++ {
 		C.free(unsafe.Pointer(cUrls[i]))
 		if ret == 0 {
-			errors[i] = &gErrorImpl{code: syscall.EAGAIN}
+			errors[i] = newOpError("bring_online", &gErrorImpl{code: syscall.EAGAIN}, urls[i])
 		} else if cErrs[i] == nil {
 			errors[i] = nil
 		} else {
-			errors[i] = errorCtoGo(cErrs[i])
+			errors[i] = opErrorCtoGo("bring_online", cErrs[i], urls[i])
 		}
 	}
 
@@ -154,7 +154,7 @@ func (context Context) BringOnlinePollList(urls []string, token string) []GError
 		if cErrs[i] == nil {
 			errors[i] = nil
 		} else {
-			errors[i] = errorCtoGo(cErrs[i])
+			errors[i] = opErrorCtoGo("bring_online_poll", cErrs[i], urls[i])
 		}
 	}
 
@@ -184,7 +184,7 @@ func (context Context) ReleaseFileList(urls []string, token string) []GError {
 		if errs[i] == nil {
 			errors[i] = nil
 		} else {
-			errors[i] = errorCtoGo(errs[i])
+			errors[i] = opErrorCtoGo("release_file", errs[i], urls[i])
 		}
 	}
 
@@ -214,7 +214,7 @@ func (context Context) AbortFiles(urls []string, token string) []GError {
 		if errs[i] == nil {
 			errors[i] = nil
 		} else {
-			errors[i] = errorCtoGo(errs[i])
+			errors[i] = opErrorCtoGo("abort_files", errs[i], urls[i])
 		}
 	}
 
@@ -0,0 +1,114 @@
+/*
+ * Copyright (c) CERN 2016
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gfal2
+
+import (
+	"sync"
+)
+
+// stream forwards values to a buffered channel without ever blocking the sender:
+// when the buffer is full, the oldest value is dropped.
+type stream[T any] struct {
+	mutex  sync.Mutex
+	ch     chan T
+	closed bool
+}
+
+func newStream[T any](buffer int) *stream[T] {
+	if buffer < 1 {
+		buffer = 1
+	}
+	return &stream[T]{ch: make(chan T, buffer)}
+}
+
+func (s *stream[T]) send(value T) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.closed {
+		return
+	}
+	for {
+		select {
+		case s.ch <- value:
+			return
+		default:
+		}
+		// Full, drop the oldest value, unless the reader got it first
+		select {
+		case <-s.ch:
+		default:
+		}
+	}
+}
+
+// close the channel. It is safe to call it more than once.
+func (s *stream[T]) close() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if !s.closed {
+		s.closed = true
+		close(s.ch)
+	}
+}
+
+type markerStream struct {
+	*stream[Marker]
+}
+
+// NotifyPerformanceMarker implements MonitorListener.
+func (s markerStream) NotifyPerformanceMarker(marker Marker) {
+	s.send(marker)
+}
+
+type eventStream struct {
+	*stream[Event]
+}
+
+// NotifyEvent implements EventListener.
+func (s eventStream) NotifyEvent(event Event) {
+	s.send(event)
+}
+
+// MarkerChannel returns a channel that receives the performance markers of the next CopyFile or CopyFiles call.
+// It never blocks the transfer: if the channel already holds buffer markers, the oldest one is dropped.
+// The channel is closed when the copy returns, or when the handler is closed.
+func (params TransferHandler) MarkerChannel(buffer int) (<-chan Marker, GError) {
+	listener := markerStream{newStream[Marker](buffer)}
+	if err := params.AddMonitorCallback(listener); err != nil {
+		return nil, err
+	}
+	params.registry.addStream(func() {
+		params.registry.removeMonitorListener(listener)
+		listener.close()
+	})
+	return listener.ch, nil
+}
+
+// EventChannel returns a channel that receives the events of the next CopyFile or CopyFiles call.
+// It never blocks the transfer: if the channel already holds buffer events, the oldest one is dropped.
+// The channel is closed when the copy returns, or when the handler is closed.
+func (params TransferHandler) EventChannel(buffer int) (<-chan Event, GError) {
+	listener := eventStream{newStream[Event](buffer)}
+	if err := params.AddEventCallback(listener); err != nil {
+		return nil, err
+	}
+	params.registry.addStream(func() {
+		params.registry.removeEventListener(listener)
+		listener.close()
+	})
+	return listener.ch, nil
+}
@@ -0,0 +1,101 @@
+package gfal2
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestStreamDropOldest(t *testing.T) {
+	s := newStream[int](3)
+	for i := 0; i < 10; i++ {
+		s.send(i)
+	}
+	s.close()
+	s.send(10)
+
+	var received []int
+	for value := range s.ch {
+		received = append(received, value)
+	}
+	if len(received) != 3 || received[0] != 7 || received[2] != 9 {
+		t.Error("Was expecting the last 3 values, got ", received)
+	}
+}
+
+func TestEventChannel(t *testing.T) {
+	context := getContext(t)
+	handler, err := context.NewTransferHandler()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer handler.Close()
+
+	events, err := handler.EventChannel(1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	source, _ := writeLocalFile(t, 1024*1024)
+	destination := "file://" + filepath.Join(t.TempDir(), "copy")
+	done := make(chan GError, 1)
+	go func() {
+		done <- handler.CopyFile(source, destination)
+	}()
+
+	// The loop ends because the channel is closed once the copy returns
+	received := 0
+	for range events {
+		received++
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if received == 0 {
+		t.Error("No event received")
+	}
+}
+
+func TestCopyDoesNotInheritChannels(t *testing.T) {
+	context := getContext(t)
+	handler, err := context.NewTransferHandler()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events, err := handler.EventChannel(1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	listener := &countingListener{}
+	handler.AddEventCallback(listener)
+
+	handlerCopy, err := handler.Copy()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer handlerCopy.Close()
+
+	_, eventListeners := handlerCopy.registry.listeners()
+	if len(eventListeners) != 1 || eventListeners[0] != EventListener(listener) {
+		t.Error("Only the plain listener should be inherited: ", eventListeners)
+	}
+
+	source, _ := writeLocalFile(t, 1024)
+	destination := "file://" + filepath.Join(t.TempDir(), "copy")
+	if err := handlerCopy.CopyFile(source, destination); err != nil {
+		t.Fatal(err)
+	}
+	if listener.events == 0 {
+		t.Error("The inherited listener was not called")
+	}
+	if len(events) != 0 {
+		t.Error("The channel of the original handler received events from the copy")
+	}
+
+	if err := handler.Close(); err != nil {
+		t.Error(err)
+	}
+	if _, open := <-events; open {
+		t.Error("The channel should be closed with its handler")
+	}
+}
@@ -0,0 +1,324 @@
+/*
+ * Copyright (c) CERN 2016
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gfal2
+
+import (
+	"errors"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// SyncOptions configures Sync. The zero value uses the defaults.
+type SyncOptions struct {
+	// Number of files copied or deleted concurrently. Defaults to 4.
+	Concurrency int
+	// If set, files of the same size are compared with this checksum type (adler32, md5...),
+	// instead of the modification time, and the copies are validated with it.
+	Checksum string
+	// Delete the files and directories of the destination that do not exist in the source.
+	Delete bool
+	// Patterns, in the syntax of path.Match, matched against the path relative to the directories,
+	// or against the base name if the pattern has no slash.
+	// If Include is not empty, only the files matching one of them are synchronised.
+	// Files and directories matching one of Exclude are ignored, and never deleted.
+	Include []string
+	Exclude []string
+	// Report what would be done, without changing anything.
+	DryRun bool
+	// If set, it is called for every copy, to configure the transfer handler (streams, timeout...).
+	// By default, the handler overwrites the destination and creates its parent directories.
+	Configure func(handler *TransferHandler) error
+}
+
+// SyncFailure describes a path that could not be synchronised.
+type SyncFailure struct {
+	Path string
+	Err  error
+}
+
+// SyncReport summarises what Sync did. Paths are relative to the synchronised directories.
+type SyncReport struct {
+	// Files missing from the destination, copied.
+	Copied []string
+	// Files that differ, copied again.
+	Updated []string
+	// Files and directories deleted from the destination.
+	Deleted []string
+	// Directories created in the destination.
+	Created []string
+	// Number of files found identical.
+	Unchanged int
+	Failed    []SyncFailure
+}
+
+// Returns true if rel, or its base name for patterns without a slash, matches any of the patterns.
+func matchAny(patterns []string, rel string) bool {
+	for _, pattern := range patterns {
+		name := rel
+		if !strings.Contains(pattern, "/") {
+			name = path.Base(rel)
+		}
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// Lists the tree under root, by path relative to it. Entries excluded by the options are skipped.
+// A missing root is an empty tree.
+func (context Context) syncList(root string, options *SyncOptions, fail func(rel string, err error)) (map[string]Stat, error) {
+	entries := make(map[string]Stat)
+	base := strings.TrimRight(root, "/")
+
+	if _, err := context.Stat(root); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return entries, nil
+		}
+		return nil, err
+	}
+
+	err := context.Walk(root, func(url string, info Stat) error {
+		rel := strings.TrimPrefix(strings.TrimPrefix(url, base), "/")
+		if rel == "" {
+			return nil
+		}
+		if matchAny(options.Exclude, rel) {
+			if info.IsDir() {
+				return SkipDir
+			}
+			return nil
+		}
+		if !info.IsDir() && len(options.Include) > 0 && !matchAny(options.Include, rel) {
+			return nil
+		}
+		entries[rel] = info
+		return nil
+	}, &WalkOptions{
+		Concurrency:  options.Concurrency,
+		SkipSymlinks: true,
+		OnDirError: func(url string, err error) error {
+			fail(strings.TrimPrefix(strings.TrimPrefix(url, base), "/"), err)
+			return nil
+		},
+	})
+	return entries, err
+}
+
+// Returns true if the source file needs to be copied over the destination one.
+func (context Context) syncDiffers(srcURL string, src Stat, dstURL string, dst Stat, options *SyncOptions) (bool, error) {
+	if src.Size() != dst.Size() {
+		return true, nil
+	}
+	if options.Checksum == "" {
+		// Copies do not preserve the modification time, so only a newer source counts as a change
+		return src.ModTime().After(dst.ModTime()), nil
+	}
+
+	srcChecksum, err := context.Checksum(srcURL, options.Checksum, 0, 0)
+	if err != nil {
+		return false, err
+	}
+	dstChecksum, err := context.Checksum(dstURL, options.Checksum, 0, 0)
+	if err != nil {
+		return false, err
+	}
+	return !checksumEqual(srcChecksum, dstChecksum), nil
+}
+
+// Copy a file with a new transfer handler.
+func (context Context) syncCopy(srcURL string, dstURL string, options *SyncOptions) error {
+	handler, gerr := context.NewTransferHandler()
+	if gerr != nil {
+		return gerr
+	}
+	defer handler.Close()
+
+	if gerr := handler.SetOverwrite(true); gerr != nil {
+		return gerr
+	}
+	if gerr := handler.SetCreateParentDir(true); gerr != nil {
+		return gerr
+	}
+	if options.Checksum != "" {
+		if gerr := handler.SetChecksum(ChecksumBoth, options.Checksum, ""); gerr != nil {
+			return gerr
+		}
+	}
+	if options.Configure != nil {
+		if err := options.Configure(handler); err != nil {
+			return err
+		}
+	}
+
+	if gerr := handler.CopyFile(srcURL, dstURL); gerr != nil {
+		return gerr
+	}
+	return nil
+}
+
+// Sync makes dstDir a copy of srcDir, like rsync.
+// Files missing from the destination, or that differ in size, modification time (the source being newer)
+// or, if opts.Checksum is set, checksum, are copied with a TransferHandler.
+// With opts.Delete, files and directories that only exist in the destination are removed.
+// Failures do not stop the process; they are collected in the report, and the first one is also
+// returned as the error. opts can be nil.
+func (context Context) Sync(srcDir string, dstDir string, opts *SyncOptions) (*SyncReport, error) {
+	var options SyncOptions
+	if opts != nil {
+		options = *opts
+	}
+	if options.Concurrency <= 0 {
+		options.Concurrency = 4
+	}
+
+	report := &SyncReport{}
+	var mutex sync.Mutex
+	fail := func(rel string, err error) {
+		mutex.Lock()
+		report.Failed = append(report.Failed, SyncFailure{Path: rel, Err: err})
+		mutex.Unlock()
+	}
+	record := func(list *[]string, rel string) {
+		mutex.Lock()
+		*list = append(*list, rel)
+		mutex.Unlock()
+	}
+
+	srcBase := strings.TrimRight(srcDir, "/")
+	dstBase := strings.TrimRight(dstDir, "/")
+
+	// A missing source would otherwise look like an empty tree, and wipe the destination with Delete
+	if _, err := context.Stat(srcDir); err != nil {
+		return report, err
+	}
+	srcEntries, err := context.syncList(srcDir, &options, fail)
+	if err != nil {
+		return report, err
+	}
+	dstEntries, err := context.syncList(dstDir, &options, fail)
+	if err != nil {
+		return report, err
+	}
+
+	// Directories first, so empty ones are replicated too. Sorted, so parents come first.
+	var dirs, files []string
+	for rel, info := range srcEntries {
+		if info.IsDir() {
+			if _, exists := dstEntries[rel]; !exists {
+				dirs = append(dirs, rel)
+			}
+		} else {
+			files = append(files, rel)
+		}
+	}
+	sort.Strings(dirs)
+	sort.Strings(files)
+
+	for _, rel := range dirs {
+		if !options.DryRun {
+			if err := context.MkdirAll(dstBase+"/"+rel, 0755); err != nil {
+				fail(rel, err)
+				continue
+			}
+		}
+		record(&report.Created, rel)
+	}
+
+	forEachConcurrent(files, options.Concurrency, func(rel string) {
+		srcURL, dstURL := srcBase+"/"+rel, dstBase+"/"+rel
+		list := &report.Copied
+
+		if dst, exists := dstEntries[rel]; exists {
+			if dst.IsDir() {
+				fail(rel, newOpError("sync", gErrorImpl{code: syscall.EISDIR, message: "destination is a directory"}, srcURL, dstURL))
+				return
+			}
+			differs, err := context.syncDiffers(srcURL, srcEntries[rel], dstURL, dst, &options)
+			if err != nil {
+				fail(rel, err)
+				return
+			}
+			if !differs {
+				mutex.Lock()
+				report.Unchanged++
+				mutex.Unlock()
+				return
+			}
+			list = &report.Updated
+		}
+
+		if !options.DryRun {
+			if err := context.syncCopy(srcURL, dstURL, &options); err != nil {
+				fail(rel, err)
+				return
+			}
+		}
+		record(list, rel)
+	})
+
+	if options.Delete {
+		var extraFiles, extraDirs []string
+		for rel, info := range dstEntries {
+			if _, exists := srcEntries[rel]; exists {
+				continue
+			}
+			if info.IsDir() {
+				extraDirs = append(extraDirs, rel)
+			} else {
+				extraFiles = append(extraFiles, rel)
+			}
+		}
+
+		forEachConcurrent(extraFiles, options.Concurrency, func(rel string) {
+			if !options.DryRun {
+				if err := context.unlink(dstBase + "/" + rel); err != nil {
+					fail(rel, err)
+					return
+				}
+			}
+			record(&report.Deleted, rel)
+		})
+
+		// Deepest first. Directories that still hold excluded files are left alone.
+		sort.Sort(sort.Reverse(sort.StringSlice(extraDirs)))
+		for _, rel := range extraDirs {
+			if !options.DryRun {
+				if err := context.rmdir(dstBase + "/" + rel); err != nil {
+					if !errors.Is(err, syscall.ENOTEMPTY) && !errors.Is(err, syscall.EEXIST) {
+						fail(rel, err)
+					}
+					continue
+				}
+			}
+			record(&report.Deleted, rel)
+		}
+	}
+
+	sort.Strings(report.Copied)
+	sort.Strings(report.Updated)
+	sort.Strings(report.Deleted)
+
+	if len(report.Failed) > 0 {
+		return report, report.Failed[0].Err
+	}
+	return report, nil
+}
@@ -0,0 +1,68 @@
+package gfal2
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSync(t *testing.T) {
+	context := getContext(t)
+	src, dst := t.TempDir(), t.TempDir()
+
+	files := map[string]string{
+		"a/one":     "1",
+		"a/b/two":   "22",
+		"skip.tmp":  "tmp",
+		"empty/":    "",
+		"unchanged": "same",
+	}
+	for name, content := range files {
+		path := filepath.Join(src, name)
+		if name[len(name)-1] == '/' {
+			os.MkdirAll(path, 0755)
+			continue
+		}
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	os.WriteFile(filepath.Join(dst, "unchanged"), []byte("same"), 0644)
+	os.WriteFile(filepath.Join(dst, "extra"), []byte("extra"), 0644)
+	os.WriteFile(filepath.Join(dst, "keep.tmp"), []byte("excluded"), 0644)
+
+	options := &SyncOptions{Delete: true, Exclude: []string{"*.tmp"}}
+	report, err := context.Sync("file://"+src, "file://"+dst, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(report.Copied, []string{"a/b/two", "a/one"}) {
+		t.Error("Unexpected copies: ", report.Copied)
+	}
+	if !reflect.DeepEqual(report.Deleted, []string{"extra"}) {
+		t.Error("Unexpected deletions: ", report.Deleted)
+	}
+	if report.Unchanged != 1 || len(report.Updated) != 0 {
+		t.Error("Unexpected report: ", report)
+	}
+	if _, err := os.Stat(filepath.Join(dst, "empty")); err != nil {
+		t.Error("Empty directory not created: ", err)
+	}
+	if _, err := os.Stat(filepath.Join(dst, "skip.tmp")); err == nil {
+		t.Error("Excluded file copied")
+	}
+	if _, err := os.Stat(filepath.Join(dst, "keep.tmp")); err != nil {
+		t.Error("Excluded file deleted")
+	}
+
+	// Nothing left to do
+	report, err = context.Sync("file://"+src, "file://"+dst, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Copied)+len(report.Updated)+len(report.Deleted) != 0 || report.Unchanged != 3 {
+		t.Error("Was expecting no changes: ", report)
+	}
+}
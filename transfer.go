@@ -17,13 +17,18 @@
 package gfal2
 
 // #cgo pkg-config: gfal2 gfal_transfer
+// #include <stdint.h>
 // #include <gfal_api.h>
 //
-// void monitorCallback(gfalt_transfer_status_t h, const char* src, const char* dst, gpointer user_data);
-// void eventCallback(const gfalt_event_t e, gpointer user_data);
+// gint addMonitorCallback(gfalt_params_t params, uintptr_t handle, GError** err);
+// gint removeMonitorCallback(gfalt_params_t params, GError** err);
+// gint addEventCallback(gfalt_params_t params, uintptr_t handle, GError** err);
+// gint removeEventCallback(gfalt_params_t params, GError** err);
 import "C"
 import (
 	"bytes"
+	"runtime/cgo"
+	"syscall"
 	"unsafe"
 )
 
@@ -44,11 +49,13 @@ const (
 
 // Event stores the data passed to the event listener.
 type Event struct {
-	Side        int
-	Timestamp   uint64
-	Stage       string
-	Domain      string
-	Description string
+	Side        int    `json:"side"`
+	Timestamp   uint64 `json:"timestamp"`
+	Stage       string `json:"stage"`
+	Domain      string `json:"domain"`
+	Description string `json:"description"`
+	// Index of the CopyPair the event refers to, for CopyFiles. Always 0 for CopyFile.
+	Pair int `json:"pair"`
 }
 
 // EventListener must be implemented by callbacks that want to be notified by
@@ -63,6 +70,11 @@ type Marker struct {
 	InstantThroughput uint64
 	BytesTransferred  uint64
 	ElapsedTime       uint64
+	// Urls of the transfer the marker refers to.
+	Source      string
+	Destination string
+	// Index of the CopyPair the marker refers to, for CopyFiles. Always 0 for CopyFile.
+	Pair int
 }
 
 // MonitorListener must be implemented by callbacks that want to be notified by the
@@ -75,12 +87,9 @@ type MonitorListener interface {
 type TransferHandler struct {
 	cParams  C.gfalt_params_t
 	cContext C.gfal2_context_t
+	registry *callbackRegistry
 }
 
-// Global references to the listeners
-var monitorListeners []MonitorListener
-var eventListeners []EventListener
-
 // NewTransferHandler creates a new TransferParameters struct.
 func (context Context) NewTransferHandler() (*TransferHandler, GError) {
 	var params TransferHandler
@@ -89,8 +98,9 @@ func (context Context) NewTransferHandler() (*TransferHandler, GError) {
 	params.cContext = context.cContext
 	params.cParams = C.gfalt_params_handle_new(&err)
 	if params.cParams == nil {
-		return nil, errorCtoGo(err)
+		return nil, opErrorCtoGo("params_new", err)
 	}
+	params.registry = newCallbackRegistry()
 
 	return &params, nil
 }
@@ -103,19 +113,49 @@ func (params TransferHandler) Copy() (*TransferHandler, GError) {
 	paramsCopy.cContext = params.cContext
 	paramsCopy.cParams = C.gfalt_params_handle_copy(params.cParams, &err)
 	if paramsCopy.cParams == nil {
-		return nil, errorCtoGo(err)
+		return nil, opErrorCtoGo("params_copy", err)
+	}
+
+	// The copy gets its own registry, with the same listeners.
+	// Drop the callbacks it may have inherited, since they point to the original one.
+	C.removeMonitorCallback(paramsCopy.cParams, &err)
+	C.g_clear_error(&err)
+	C.removeEventCallback(paramsCopy.cParams, &err)
+	C.g_clear_error(&err)
+
+	paramsCopy.registry = newCallbackRegistry()
+	monitorListeners, eventListeners := params.registry.listeners()
+	for _, listener := range monitorListeners {
+		// Channels are bound to the original handler
+		if _, isStream := listener.(markerStream); isStream {
+			continue
+		}
+		if gerr := paramsCopy.AddMonitorCallback(listener); gerr != nil {
+			paramsCopy.Close()
+			return nil, gerr
+		}
+	}
+	for _, listener := range eventListeners {
+		if _, isStream := listener.(eventStream); isStream {
+			continue
+		}
+		if gerr := paramsCopy.AddEventCallback(listener); gerr != nil {
+			paramsCopy.Close()
+			return nil, gerr
+		}
 	}
 
 	return &paramsCopy, nil
 }
 
-// Close destroys the TransferParameters.
+// Close destroys the TransferParameters, and releases the listeners.
 func (params TransferHandler) Close() GError {
 	var err *C.GError
 
 	C.gfalt_params_handle_delete(params.cParams, &err)
+	params.registry.release()
 	if err != nil {
-		errorCtoGo(err)
+		return opErrorCtoGo("params_delete", err)
 	}
 	return nil
 }
@@ -126,7 +166,7 @@ func (params TransferHandler) SetTimeout(timeout int) GError {
 
 	ret := C.gfalt_set_timeout(params.cParams, C.guint64(timeout), &err)
 	if ret < 0 {
-		return errorCtoGo(err)
+		return opErrorCtoGo("set_timeout", err)
 	}
 	return nil
 }
@@ -149,7 +189,7 @@ func (params TransferHandler) SetNoStreams(nostreams int) GError {
 
 	ret := C.gfalt_set_nbstreams(params.cParams, C.guint(nostreams), &err)
 	if ret < 0 {
-		return errorCtoGo(err)
+		return opErrorCtoGo("set_nbstreams", err)
 	}
 	return nil
 }
@@ -172,7 +212,7 @@ func (params TransferHandler) SetTCPBuffersize(size int) GError {
 
 	ret := C.gfalt_set_tcp_buffer_size(params.cParams, C.guint64(size), &err)
 	if ret < 0 {
-		return errorCtoGo(err)
+		return opErrorCtoGo("set_tcp_buffer_size", err)
 	}
 	return nil
 }
@@ -198,7 +238,7 @@ func (params TransferHandler) SetSourceSpacetoken(token string) GError {
 
 	ret := C.gfalt_set_src_spacetoken(params.cParams, cToken, &err)
 	if ret < 0 {
-		return errorCtoGo(err)
+		return opErrorCtoGo("set_src_spacetoken", err)
 	}
 	return nil
 }
@@ -224,7 +264,7 @@ func (params TransferHandler) SetDestinationSpaceToken(token string) GError {
 
 	ret := C.gfalt_set_dst_spacetoken(params.cParams, cToken, &err)
 	if ret < 0 {
-		return errorCtoGo(err)
+		return opErrorCtoGo("set_dst_spacetoken", err)
 	}
 	return nil
 }
@@ -252,7 +292,7 @@ func (params TransferHandler) SetOverwrite(overwrite bool) GError {
 
 	ret := C.gfalt_set_replace_existing_file(params.cParams, cOverwrite, &err)
 	if ret < 0 {
-		return errorCtoGo(err)
+		return opErrorCtoGo("set_replace_existing_file", err)
 	}
 	return nil
 }
@@ -280,7 +320,7 @@ func (params TransferHandler) SetStrictCopy(strict bool) GError {
 
 	ret := C.gfalt_set_strict_copy_mode(params.cParams, cStrict, &err)
 	if ret < 0 {
-		return errorCtoGo(err)
+		return opErrorCtoGo("set_strict_copy_mode", err)
 	}
 	return nil
 }
@@ -302,7 +342,7 @@ func (params TransferHandler) GetChecksumMode() (int, GError) {
 
 	ret := C.gfalt_get_checksum_mode(params.cParams, &err)
 	if err != nil {
-		return -1, errorCtoGo(err)
+		return -1, opErrorCtoGo("get_checksum_mode", err)
 	}
 	return int(ret), nil
 }
@@ -319,7 +359,7 @@ func (params TransferHandler) SetChecksum(mode int, chktype string, chkvalue str
 
 	ret := C.gfalt_set_checksum(params.cParams, C.gfalt_checksum_mode_t(mode), cType, cValue, &err)
 	if ret < 0 {
-		return errorCtoGo(err)
+		return opErrorCtoGo("set_checksum", err)
 	}
 	return nil
 }
@@ -355,7 +395,7 @@ func (params TransferHandler) SetCreateParentDir(create bool) GError {
 
 	ret := C.gfalt_set_create_parent_dir(params.cParams, cCreate, &err)
 	if ret < 0 {
-		return errorCtoGo(err)
+		return opErrorCtoGo("set_create_parent_dir", err)
 	}
 	return nil
 }
@@ -373,10 +413,10 @@ func (params TransferHandler) GetCreateParentDir() bool {
 
 // Wrapper for callbacks
 //export monitorCallbackWrapper
-func monitorCallbackWrapper(h C.gfalt_transfer_status_t, src *C.char, dst *C.char, userData C.gpointer) {
+func monitorCallbackWrapper(h C.gfalt_transfer_status_t, src *C.char, dst *C.char, userData C.uintptr_t) {
 	var err *C.GError
 
-	listener := uintptr(userData)
+	registry := cgo.Handle(userData).Value().(*callbackRegistry)
 
 	var marker Marker
 	marker.AvgThroughput = uint64(C.gfalt_copy_get_average_baudrate(h, &err))
@@ -387,34 +427,52 @@ func monitorCallbackWrapper(h C.gfalt_transfer_status_t, src *C.char, dst *C.cha
 	C.g_clear_error(&err)
 	marker.ElapsedTime = uint64(C.gfalt_copy_get_elapsed_time(h, &err))
 	C.g_clear_error(&err)
+	marker.Source = C.GoString(src)
+	marker.Destination = C.GoString(dst)
+	if bulk := registry.currentBulk(); bulk != nil {
+		marker.Pair = bulk.markerPair(marker.Source, marker.Destination)
+	}
 
-	monitorListeners[listener].NotifyPerformanceMarker(marker)
+	monitorListeners, _ := registry.listeners()
+	for _, listener := range monitorListeners {
+		listener.NotifyPerformanceMarker(marker)
+	}
 }
 
 // AddMonitorCallback adds a function to be called with the performance markers data.
+// It is safe to call it concurrently with a running transfer.
 func (params TransferHandler) AddMonitorCallback(listener MonitorListener) GError {
-	var err *C.GError
+	registry := params.registry
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+
+	// A single callback is registered with gfal2, and it dispatches to all the listeners
+	if !registry.monitorInstalled {
+		var err *C.GError
+		ret := C.addMonitorCallback(params.cParams, C.uintptr_t(registry.handle), &err)
+		if ret < 0 {
+			return opErrorCtoGo("add_monitor_callback", err)
+		}
+		registry.monitorInstalled = true
+	}
 
-	monitorListeners = append(monitorListeners, listener)
+	registry.monitorListeners = append(registry.monitorListeners, listener)
+	return nil
+}
 
-	ret := C.gfalt_add_monitor_callback(
-		params.cParams,
-		C.gfalt_monitor_func(C.monitorCallback),
-		C.gpointer(unsafe.Pointer(uintptr(len(monitorListeners)-1))),
-		nil,
-		&err,
-	)
-	if ret < 0 {
-		return errorCtoGo(err)
+// RemoveMonitorCallback detaches a listener added with AddMonitorCallback.
+// listener must be the same value, so it must be comparable (i.e. a pointer).
+func (params TransferHandler) RemoveMonitorCallback(listener MonitorListener) GError {
+	if !params.registry.removeMonitorListener(listener) {
+		return newOpError("remove_monitor_callback", gErrorImpl{code: syscall.ENOENT, message: "listener not registered"})
 	}
-
 	return nil
 }
 
 // Wrapper for callbacks
 //export eventCallbackWrapper
-func eventCallbackWrapper(cEvent C.gfalt_event_t, userData C.gpointer) {
-	listener := uintptr(userData)
+func eventCallbackWrapper(cEvent C.gfalt_event_t, userData C.uintptr_t) {
+	registry := cgo.Handle(userData).Value().(*callbackRegistry)
 
 	var event Event
 	event.Description = C.GoString(cEvent.description)
@@ -422,27 +480,42 @@ func eventCallbackWrapper(cEvent C.gfalt_event_t, userData C.gpointer) {
 	event.Side = int(cEvent.side)
 	event.Stage = C.GoString((*C.char)(C.g_quark_to_string(cEvent.stage)))
 	event.Timestamp = uint64(cEvent.timestamp)
+	if bulk := registry.currentBulk(); bulk != nil {
+		event.Pair = bulk.eventPair(event.Description)
+	}
 
-	eventListeners[listener].NotifyEvent(event)
+	_, eventListeners := registry.listeners()
+	for _, listener := range eventListeners {
+		listener.NotifyEvent(event)
+	}
 }
 
 // AddEventCallback adds a function to be called when there are events triggered by the plugins.
+// It is safe to call it concurrently with a running transfer.
 func (params TransferHandler) AddEventCallback(listener EventListener) GError {
-	var err *C.GError
+	registry := params.registry
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+
+	if !registry.eventInstalled {
+		var err *C.GError
+		ret := C.addEventCallback(params.cParams, C.uintptr_t(registry.handle), &err)
+		if ret < 0 {
+			return opErrorCtoGo("add_event_callback", err)
+		}
+		registry.eventInstalled = true
+	}
 
-	eventListeners = append(eventListeners, listener)
+	registry.eventListeners = append(registry.eventListeners, listener)
+	return nil
+}
 
-	ret := C.gfalt_add_event_callback(
-		params.cParams,
-		C.gfalt_event_func(C.eventCallback),
-		C.gpointer(unsafe.Pointer(uintptr(len(eventListeners)-1))),
-		nil,
-		&err,
-	)
-	if ret < 0 {
-		return errorCtoGo(err)
+// RemoveEventCallback detaches a listener added with AddEventCallback.
+// listener must be the same value, so it must be comparable (i.e. a pointer).
+func (params TransferHandler) RemoveEventCallback(listener EventListener) GError {
+	if !params.registry.removeEventListener(listener) {
+		return newOpError("remove_event_callback", gErrorImpl{code: syscall.ENOENT, message: "listener not registered"})
 	}
-
 	return nil
 }
 
@@ -451,6 +524,7 @@ func (params TransferHandler) AddEventCallback(listener EventListener) GError {
 // If the protocol does not support third party copies, then the data will be streamed via the local node.
 func (params TransferHandler) CopyFile(source string, destination string) GError {
 	var err *C.GError
+	defer params.registry.closeStreams()
 
 	cSource := C.CString(source)
 	defer C.free(unsafe.Pointer(cSource))
@@ -459,7 +533,7 @@ func (params TransferHandler) CopyFile(source string, destination string) GError
 
 	ret := C.gfalt_copy_file(params.cContext, params.cParams, cSource, cDestination, &err)
 	if ret < 0 {
-		return errorCtoGo(err)
+		return opErrorCtoGo("copy", err, source, destination)
 	}
 	return nil
 }
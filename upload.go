@@ -0,0 +1,364 @@
+/*
+ * Copyright (c) CERN 2016
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gfal2
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"hash/adler32"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// UploadOptions configures a parallel upload. The zero value uses the defaults.
+type UploadOptions struct {
+	// Number of byte ranges transferred concurrently. Defaults to 4.
+	Streams int
+	// Size of each byte range. If 0, the file is split into Streams ranges of the same size.
+	ChunkSize int64
+	// Number of times a range is retried on a transient error. Defaults to 3. Negative disables retries.
+	Retries int
+	// Time to wait between retries. Defaults to one second.
+	RetryDelay time.Duration
+	// Permissions of the remote file. Defaults to 0644.
+	Mode os.FileMode
+	// Checksum algorithm used to validate the upload (adler32, md5, sha1 or sha256).
+	// If empty, the upload is not validated.
+	ChecksumType string
+	// If set, it is notified periodically with the progress of the upload.
+	Listener MonitorListener
+	// Interval between performance markers. Defaults to one second.
+	MarkerInterval time.Duration
+}
+
+// Return a copy of opts with the defaults applied. opts can be nil.
+func (opts *UploadOptions) withDefaults() UploadOptions {
+	var final UploadOptions
+	if opts != nil {
+		final = *opts
+	}
+	applyTransferDefaults(&final.Streams, &final.Retries, &final.RetryDelay)
+	if final.Mode == 0 {
+		final.Mode = 0644
+	}
+	if final.MarkerInterval <= 0 {
+		final.MarkerInterval = time.Second
+	}
+	return final
+}
+
+// Create a hash for the given gfal2 checksum algorithm.
+// crc32 is not supported, since gfal2 does not format it as an hexadecimal string.
+func newChecksumHash(chktype string) (hash.Hash, error) {
+	switch strings.ToLower(chktype) {
+	case "adler32":
+		return adler32.New(), nil
+	case "md5":
+		return md5.New(), nil
+	case "sha1":
+		return sha1.New(), nil
+	case "sha256":
+		return sha256.New(), nil
+	}
+	return nil, &gErrorImpl{code: syscall.ENOTSUP, message: fmt.Sprintf("unsupported checksum type %s", chktype)}
+}
+
+// Compute the checksum of a local file, formatted as gfal2 does.
+func localChecksum(path string, chktype string) (string, error) {
+	h, err := newChecksumHash(chktype)
+	if err != nil {
+		return "", err
+	}
+
+	fd, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer fd.Close()
+
+	if _, err = io.Copy(h, fd); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
+
+// Compare two checksums. Some storages strip the leading zeros, or use upper case.
+func checksumEqual(a string, b string) bool {
+	a = strings.TrimLeft(strings.ToLower(a), "0")
+	b = strings.TrimLeft(strings.ToLower(b), "0")
+	return a == b
+}
+
+// progressReporter sends periodically a Marker to a MonitorListener.
+type progressReporter struct {
+	listener MonitorListener
+	start    time.Time
+	bytes    atomic.Uint64
+	done     chan struct{}
+	wg       sync.WaitGroup
+}
+
+// Start a progressReporter. Returns nil if listener is nil.
+func startProgressReporter(listener MonitorListener, interval time.Duration) *progressReporter {
+	if listener == nil {
+		return nil
+	}
+	reporter := &progressReporter{
+		listener: listener,
+		start:    time.Now(),
+		done:     make(chan struct{}),
+	}
+	reporter.wg.Add(1)
+	go reporter.run(interval)
+	return reporter
+}
+
+// Add n bytes to the count of transferred bytes. Safe to call on a nil reporter.
+func (reporter *progressReporter) add(n int) {
+	if reporter != nil {
+		reporter.bytes.Add(uint64(n))
+	}
+}
+
+// Reset the count of transferred bytes, when starting over. Safe to call on a nil reporter.
+func (reporter *progressReporter) reset() {
+	if reporter != nil {
+		reporter.bytes.Store(0)
+	}
+}
+
+// Stop the reporter, sending a last marker. Safe to call on a nil reporter.
+func (reporter *progressReporter) stop() {
+	if reporter != nil {
+		close(reporter.done)
+		reporter.wg.Wait()
+	}
+}
+
+func (reporter *progressReporter) run(interval time.Duration) {
+	defer reporter.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	lastBytes := uint64(0)
+	lastTime := reporter.start
+	for {
+		var now time.Time
+		final := false
+		select {
+		case now = <-ticker.C:
+		case <-reporter.done:
+			now = time.Now()
+			final = true
+		}
+
+		var marker Marker
+		marker.BytesTransferred = reporter.bytes.Load()
+		elapsed := now.Sub(reporter.start)
+		marker.ElapsedTime = uint64(elapsed / time.Second)
+		if elapsed > 0 {
+			marker.AvgThroughput = uint64(float64(marker.BytesTransferred) / elapsed.Seconds())
+		}
+		if marker.BytesTransferred < lastBytes {
+			// The counter was reset
+			lastBytes = 0
+		}
+		if delta := now.Sub(lastTime); delta > 0 {
+			marker.InstantThroughput = uint64(float64(marker.BytesTransferred-lastBytes) / delta.Seconds())
+		}
+		lastBytes, lastTime = marker.BytesTransferred, now
+
+		reporter.listener.NotifyPerformanceMarker(marker)
+		if final {
+			return
+		}
+	}
+}
+
+// Returns true if the error means the protocol does not support positional writes.
+func isPositionalNotSupported(err error) bool {
+	code, ok := errorCode(err)
+	return ok && (code == syscall.ENOTSUP || code == syscall.ENOSYS || code == syscall.ESPIPE)
+}
+
+// Upload copies the local file localPath into the remote url, writing several byte ranges
+// concurrently with positional writes.
+// If the protocol does not support positional writes, the file is written sequentially instead.
+// Each range is retried independently on transient errors.
+// If opts.ChecksumType is set, the remote checksum is compared with the local one once the upload is done.
+func (context Context) Upload(localPath string, url string, opts *UploadOptions) error {
+	options := opts.withDefaults()
+
+	var expected string
+	if options.ChecksumType != "" {
+		var err error
+		if expected, err = localChecksum(localPath, options.ChecksumType); err != nil {
+			return err
+		}
+	}
+
+	local, err := os.Open(localPath)
+	if err != nil {
+		return err
+	}
+	defer local.Close()
+
+	info, err := local.Stat()
+	if err != nil {
+		return err
+	}
+
+	reporter := startProgressReporter(options.Listener, options.MarkerInterval)
+	err = context.uploadPositional(local, url, info.Size(), options, reporter)
+	if isPositionalNotSupported(err) {
+		// The same reporter is kept, so the elapsed time and the markers stay continuous,
+		// but the file is written again from the start
+		reporter.reset()
+		err = context.uploadSequential(local, url, options, reporter)
+	}
+	reporter.stop()
+	if err != nil {
+		return err
+	}
+
+	if options.ChecksumType != "" {
+		remote, gerr := context.Checksum(url, options.ChecksumType, 0, 0)
+		if gerr != nil {
+			return gerr
+		}
+		if !checksumEqual(expected, remote) {
+			return newOpError("upload", &gErrorImpl{
+				code:    syscall.EIO,
+				message: fmt.Sprintf("checksum mismatch: local %s %s, remote %s", options.ChecksumType, expected, remote),
+			}, localPath, url)
+		}
+	}
+
+	return nil
+}
+
+// Upload local into url using concurrent positional writes over a single remote descriptor.
+// The first block is written before spawning the workers, so an unsupported operation is detected early.
+func (context Context) uploadPositional(local *os.File, url string, size int64, options UploadOptions, reporter *progressReporter) (err error) {
+	remote, gerr := context.OpenFile(url, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, options.Mode)
+	if gerr != nil {
+		return gerr
+	}
+	defer func() {
+		if closeErr := remote.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+
+	chunks := splitChunks(size, options.Streams, options.ChunkSize)
+	if len(chunks) == 0 {
+		return nil
+	}
+
+	writeBlock := func(buffer []byte, offset int64) error {
+		read, err := local.ReadAt(buffer, offset)
+		if err != nil && !(err == io.EOF && read == len(buffer)) {
+			return err
+		}
+		for attempt := 0; ; attempt++ {
+			_, err = remote.WriteAt(buffer, offset)
+			if err == nil || attempt >= options.Retries || !IsTransient(err) {
+				break
+			}
+			time.Sleep(options.RetryDelay)
+		}
+		if err == nil {
+			reporter.add(len(buffer))
+		}
+		return err
+	}
+
+	writeChunk := func(c chunk, buffer []byte) error {
+		for done := int64(0); done < c.length; {
+			n := c.length - done
+			if n > int64(len(buffer)) {
+				n = int64(len(buffer))
+			}
+			if err := writeBlock(buffer[:n], c.offset+done); err != nil {
+				return err
+			}
+			done += n
+		}
+		return nil
+	}
+
+	// Probe with the first block of the first chunk
+	probeSize := chunks[0].length
+	if probeSize > copyBufferSize {
+		probeSize = copyBufferSize
+	}
+	if err := writeBlock(make([]byte, probeSize), 0); err != nil {
+		return err
+	}
+	chunks[0].offset += probeSize
+	chunks[0].length -= probeSize
+
+	buffers := make([][]byte, options.Streams)
+	return processChunks(chunks, options.Streams, func(worker int, c chunk) error {
+		if buffers[worker] == nil {
+			buffers[worker] = make([]byte, copyBufferSize)
+		}
+		return writeChunk(c, buffers[worker])
+	})
+}
+
+// Upload local into url writing sequentially.
+func (context Context) uploadSequential(local *os.File, url string, options UploadOptions, reporter *progressReporter) (err error) {
+	if _, err = local.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	remote, gerr := context.OpenFile(url, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, options.Mode)
+	if gerr != nil {
+		return gerr
+	}
+	defer func() {
+		if closeErr := remote.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+
+	buffer := make([]byte, copyBufferSize)
+	for {
+		read, readErr := local.Read(buffer)
+		if read > 0 {
+			if _, err = remote.Write(buffer[:read]); err != nil {
+				return err
+			}
+			reporter.add(read)
+		}
+		if readErr == io.EOF {
+			return nil
+		} else if readErr != nil {
+			return readErr
+		}
+	}
+}
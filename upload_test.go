@@ -0,0 +1,68 @@
+package gfal2
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+type lastMarkerListener struct {
+	count int
+	last  Marker
+}
+
+func (listener *lastMarkerListener) NotifyPerformanceMarker(marker Marker) {
+	listener.count++
+	listener.last = marker
+}
+
+func TestUpload(t *testing.T) {
+	context := getContext(t)
+	url, content := writeLocalFile(t, 1024*1024+17)
+	source := strings.TrimPrefix(url, "file://")
+	destination := filepath.Join(t.TempDir(), "upload")
+
+	listener := &lastMarkerListener{}
+	err := context.Upload(source, "file://"+destination, &UploadOptions{
+		Streams:        4,
+		ChunkSize:      64 * 1024,
+		ChecksumType:   "adler32",
+		Listener:       listener,
+		MarkerInterval: time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	uploaded, err := os.ReadFile(destination)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(uploaded, content) {
+		t.Error("Unexpected content, got ", len(uploaded), " bytes")
+	}
+	if listener.count == 0 || listener.last.BytesTransferred != uint64(len(content)) {
+		t.Error("Unexpected last marker: ", listener.count, listener.last)
+	}
+}
+
+func TestUploadChecksumType(t *testing.T) {
+	context := getContext(t)
+	url, _ := writeLocalFile(t, 1024)
+	source := strings.TrimPrefix(url, "file://")
+	destination := filepath.Join(t.TempDir(), "upload")
+
+	if err := context.Upload(source, "file://"+destination, &UploadOptions{ChecksumType: "md5"}); err != nil {
+		t.Error(err)
+	}
+
+	for _, chktype := range []string{"unknown", "crc32"} {
+		err := context.Upload(source, "file://"+destination, &UploadOptions{ChecksumType: chktype})
+		if code, _ := errorCode(err); code != syscall.ENOTSUP {
+			t.Error(chktype, ": was expecting ENOTSUP, got ", err)
+		}
+	}
+}
@@ -0,0 +1,267 @@
+/*
+ * Copyright (c) CERN 2016
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gfal2
+
+import (
+	"io/fs"
+	"os"
+	"strings"
+	"sync"
+)
+
+// SkipDir can be returned by a WalkFunc to skip a directory. It is the same value as fs.SkipDir.
+var SkipDir = fs.SkipDir
+
+// SkipAll can be returned by a WalkFunc to stop the walk without error. It is the same value as fs.SkipAll.
+var SkipAll = fs.SkipAll
+
+// WalkFunc is called by Walk for each visited entry.
+// If it returns SkipDir when called for a directory, its content is not visited.
+// If it returns SkipDir for a file, the remaining entries of its parent directory are skipped.
+// If it returns SkipAll, the walk stops and Walk returns nil.
+// Any other error stops the walk, and is returned by Walk.
+type WalkFunc func(url string, info Stat) error
+
+// WalkOptions configures Walk. The zero value uses the defaults.
+type WalkOptions struct {
+	// Number of directories read concurrently. Defaults to 4.
+	Concurrency int
+	// Maximum depth to descend into. The entries of root are at depth 1. 0 means no limit.
+	MaxDepth int
+	// Descend into symbolic links pointing to directories. Beware, there is no loop detection,
+	// so set MaxDepth when enabling this.
+	FollowSymlinks bool
+	// Do not report symbolic links at all.
+	SkipSymlinks bool
+	// Called when a directory can not be opened or read. If it returns nil, the walk goes on.
+	// If it returns SkipAll, the walk stops without error. Any other error stops the walk.
+	// If not set, the first directory error stops the walk, and it is returned by Walk.
+	OnDirError func(url string, err error) error
+}
+
+// walkItem is a directory pending to be read.
+type walkItem struct {
+	url   string
+	depth int
+}
+
+// walker holds the state of a concurrent walk.
+type walker struct {
+	context Context
+	fn      WalkFunc
+	options WalkOptions
+
+	mutex   sync.Mutex
+	cond    *sync.Cond
+	queue   []walkItem
+	pending int
+	stopped bool
+	err     error
+
+	fnMutex sync.Mutex
+}
+
+// Walk visits the tree rooted at root, calling fn for root and for each entry underneath.
+// Directories are read with Opendir/Readdir by several goroutines. fn is never called concurrently,
+// but the order of the visit is not deterministic, except that a directory is always reported
+// before its content.
+func (context Context) Walk(root string, fn WalkFunc, opts *WalkOptions) error {
+	w := &walker{context: context, fn: fn}
+	if opts != nil {
+		w.options = *opts
+	}
+	if w.options.Concurrency <= 0 {
+		w.options.Concurrency = 4
+	}
+	w.cond = sync.NewCond(&w.mutex)
+
+	info, gerr := context.Stat(root)
+	if gerr != nil {
+		w.dirError(root, gerr)
+		return w.result()
+	}
+
+	if err := w.call(root, info); err != nil {
+		if err == SkipDir {
+			return nil
+		}
+		return w.result()
+	}
+	if !info.IsDir() {
+		return nil
+	}
+
+	w.push(walkItem{url: root, depth: 0})
+
+	var wg sync.WaitGroup
+	for i := 0; i < w.options.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.work()
+		}()
+	}
+	wg.Wait()
+
+	return w.result()
+}
+
+// Return the final result of the walk.
+func (w *walker) result() error {
+	if w.err == SkipAll {
+		return nil
+	}
+	return w.err
+}
+
+// Queue a directory.
+func (w *walker) push(item walkItem) {
+	w.mutex.Lock()
+	w.queue = append(w.queue, item)
+	w.pending++
+	w.mutex.Unlock()
+	w.cond.Signal()
+}
+
+// Stop the walk, recording the first error.
+func (w *walker) stop(err error) {
+	w.mutex.Lock()
+	if !w.stopped {
+		w.stopped = true
+		w.err = err
+	}
+	w.mutex.Unlock()
+	w.cond.Broadcast()
+}
+
+// Worker loop. Returns when there are no more directories to read, or the walk stopped.
+func (w *walker) work() {
+	for {
+		w.mutex.Lock()
+		for len(w.queue) == 0 && w.pending > 0 && !w.stopped {
+			w.cond.Wait()
+		}
+		if w.stopped || w.pending == 0 {
+			w.mutex.Unlock()
+			w.cond.Broadcast()
+			return
+		}
+		last := len(w.queue) - 1
+		item := w.queue[last]
+		w.queue = w.queue[:last]
+		w.mutex.Unlock()
+
+		w.readDir(item)
+
+		w.mutex.Lock()
+		w.pending--
+		done := w.pending == 0
+		w.mutex.Unlock()
+		if done {
+			w.cond.Broadcast()
+		}
+	}
+}
+
+// Call fn, serialized. Returns SkipAll if the walk has already stopped.
+// Any error other than SkipDir stops the walk.
+func (w *walker) call(url string, info Stat) error {
+	w.fnMutex.Lock()
+	defer w.fnMutex.Unlock()
+
+	w.mutex.Lock()
+	stopped := w.stopped
+	w.mutex.Unlock()
+	if stopped {
+		return SkipAll
+	}
+	err := w.fn(url, info)
+	if err != nil && err != SkipDir {
+		// Stopped before releasing the lock, so fn is not called again
+		w.stop(err)
+	}
+	return err
+}
+
+// Handle an error opening or reading a directory.
+func (w *walker) dirError(url string, err error) {
+	if w.options.OnDirError == nil {
+		w.stop(err)
+		return
+	}
+
+	w.fnMutex.Lock()
+	ret := w.options.OnDirError(url, err)
+	w.fnMutex.Unlock()
+	if ret != nil {
+		w.stop(ret)
+	}
+}
+
+// Read a directory, reporting its entries and queueing its subdirectories.
+func (w *walker) readDir(item walkItem) {
+	dir, gerr := w.context.Opendir(item.url)
+	if gerr != nil {
+		w.dirError(item.url, gerr)
+		return
+	}
+	defer dir.Close()
+
+	base := strings.TrimRight(item.url, "/")
+	depth := item.depth + 1
+	descend := w.options.MaxDepth <= 0 || depth < w.options.MaxDepth
+
+	for {
+		info, gerr := dir.Readdir()
+		if gerr != nil {
+			w.dirError(item.url, gerr)
+			return
+		} else if info == nil {
+			return
+		}
+
+		name := info.Name()
+		if name == "." || name == ".." {
+			continue
+		}
+		url := base + "/" + name
+
+		isDir := info.IsDir()
+		if info.Mode()&os.ModeSymlink != 0 {
+			if w.options.SkipSymlinks {
+				continue
+			}
+			isDir = false
+			if w.options.FollowSymlinks && descend {
+				if target, err := w.context.Stat(url); err == nil {
+					isDir = target.IsDir()
+				}
+			}
+		}
+
+		switch err := w.call(url, info); {
+		case err == SkipDir && isDir:
+			continue
+		case err != nil:
+			return
+		}
+
+		if isDir && descend {
+			w.push(walkItem{url: url, depth: depth})
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package gfal2
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Create the given files under a temporary directory. Names ending with a slash are directories.
+func makeTree(t *testing.T, names ...string) string {
+	root := t.TempDir()
+	for _, name := range names {
+		path := filepath.Join(root, name)
+		if strings.HasSuffix(name, "/") {
+			if err := os.MkdirAll(path, 0755); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+var walkTree = []string{"a/one", "a/two", "a/sub/three", "b/four", "empty/", "flat/1", "flat/2", "flat/3", "five"}
+
+// Walk root, returning how many times each path, relative to root, was visited.
+func walkCount(t *testing.T, root string, fn WalkFunc, opts *WalkOptions) (map[string]int, error) {
+	context := getContext(t)
+	base := "file://" + root
+	visited := make(map[string]int)
+	err := context.Walk(base, func(url string, info Stat) error {
+		rel := strings.TrimPrefix(strings.TrimPrefix(url, base), "/")
+		visited[rel]++
+		if fn != nil {
+			return fn(rel, info)
+		}
+		return nil
+	}, opts)
+	return visited, err
+}
+
+func TestWalk(t *testing.T) {
+	root := makeTree(t, walkTree...)
+	visited, err := walkCount(t, root, nil, &WalkOptions{Concurrency: 8})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"", "a", "a/one", "a/two", "a/sub", "a/sub/three", "b", "b/four", "empty", "flat", "flat/1", "flat/2", "flat/3", "five"}
+	if len(visited) != len(expected) {
+		t.Error("Unexpected visit: ", visited)
+	}
+	for _, rel := range expected {
+		if visited[rel] != 1 {
+			t.Errorf("%q visited %d times", rel, visited[rel])
+		}
+	}
+}
+
+func TestWalkSkip(t *testing.T) {
+	root := makeTree(t, walkTree...)
+
+	visited, err := walkCount(t, root, func(rel string, info Stat) error {
+		if rel == "a" {
+			return SkipDir
+		}
+		return nil
+	}, &WalkOptions{Concurrency: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if visited["a"] != 1 || visited["a/one"] != 0 || visited["a/sub/three"] != 0 || visited["b/four"] != 1 {
+		t.Error("Unexpected visit skipping a directory: ", visited)
+	}
+
+	// Skipping on a file skips the rest of its directory, whatever the order of the entries
+	visited, err = walkCount(t, root, func(rel string, info Stat) error {
+		if strings.HasPrefix(rel, "flat/") {
+			return SkipDir
+		}
+		return nil
+	}, &WalkOptions{Concurrency: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := visited["flat/1"] + visited["flat/2"] + visited["flat/3"]; n != 1 || visited["a/sub/three"] != 1 {
+		t.Error("Unexpected visit skipping a file: ", visited)
+	}
+
+	// Nothing is visited after SkipAll
+	visited, err = walkCount(t, root, func(rel string, info Stat) error {
+		if rel != "" {
+			return SkipAll
+		}
+		return nil
+	}, &WalkOptions{Concurrency: 4})
+	if err != nil {
+		t.Error("SkipAll should not be returned: ", err)
+	}
+	if len(visited) != 2 {
+		t.Error("Unexpected visit with SkipAll: ", visited)
+	}
+
+	stop := errors.New("stop")
+	_, err = walkCount(t, root, func(rel string, info Stat) error {
+		if rel == "a/sub" {
+			return stop
+		}
+		return nil
+	}, &WalkOptions{Concurrency: 4})
+	if err != stop {
+		t.Error("Was expecting the error of the WalkFunc, got ", err)
+	}
+}
+
+func TestWalkMaxDepth(t *testing.T) {
+	root := makeTree(t, walkTree...)
+	visited, err := walkCount(t, root, nil, &WalkOptions{Concurrency: 4, MaxDepth: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for rel := range visited {
+		if strings.Contains(rel, "/") {
+			t.Error("Visited beyond the maximum depth: ", rel)
+		}
+	}
+	if len(visited) != 6 {
+		t.Error("Unexpected visit: ", visited)
+	}
+}
+
+func TestWalkDirError(t *testing.T) {
+	root := makeTree(t, append(walkTree, "gone/")...)
+	// The directory is removed once reported, so it can not be opened when its turn comes
+	removeGone := func(rel string, info Stat) error {
+		if rel == "gone" {
+			os.Remove(filepath.Join(root, "gone"))
+		}
+		return nil
+	}
+
+	var failed []string
+	visited, err := walkCount(t, root, removeGone, &WalkOptions{
+		Concurrency: 4,
+		OnDirError: func(url string, err error) error {
+			failed = append(failed, url)
+			if !errors.Is(err, os.ErrNotExist) {
+				t.Error("Unexpected error: ", err)
+			}
+			return nil
+		},
+	})
+	if err != nil {
+		t.Error("The walk should have gone on: ", err)
+	}
+	if len(failed) != 1 || !strings.HasSuffix(failed[0], "/gone") {
+		t.Error("Unexpected directory errors: ", failed)
+	}
+	if visited["a/sub/three"] != 1 || visited["five"] != 1 {
+		t.Error("Unexpected visit: ", visited)
+	}
+
+	os.Mkdir(filepath.Join(root, "gone"), 0755)
+	stop := errors.New("stop")
+	_, err = walkCount(t, root, removeGone, &WalkOptions{
+		Concurrency: 4,
+		OnDirError: func(url string, err error) error {
+			return stop
+		},
+	})
+	if err != stop {
+		t.Error("Was expecting the error of OnDirError, got ", err)
+	}
+
+	os.Mkdir(filepath.Join(root, "gone"), 0755)
+	_, err = walkCount(t, root, removeGone, &WalkOptions{Concurrency: 4})
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Error("Was expecting the directory error, got ", err)
+	}
+}